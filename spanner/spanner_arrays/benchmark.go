@@ -0,0 +1,59 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// benchmarkCase is one of the approaches compared by --benchmark.
+type benchmarkCase struct {
+	name string
+	run  func(context.Context) error
+}
+
+// benchmarkResult is the average latency of a benchmarkCase.
+type benchmarkResult struct {
+	name    string
+	average time.Duration
+}
+
+// runBenchmark runs every case iterations times in turn and returns the average latency of
+// each. Running the cases alternately rather than one after the other spreads out the effect
+// of warming up the session pool and of load on the instance.
+func runBenchmark(ctx context.Context, iterations int, cases []benchmarkCase) ([]benchmarkResult, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("invalid --benchmark-iterations %d, must be positive", iterations)
+	}
+	totals := make([]time.Duration, len(cases))
+	for i := 0; i < iterations; i++ {
+		for j, c := range cases {
+			start := time.Now()
+			if err := c.run(ctx); err != nil {
+				return nil, fmt.Errorf("%s: %v", c.name, err)
+			}
+			totals[j] += time.Since(start)
+		}
+	}
+	results := make([]benchmarkResult, len(cases))
+	for j, c := range cases {
+		results[j] = benchmarkResult{name: c.name, average: totals[j] / time.Duration(iterations)}
+	}
+	return results, nil
+}
+
+// printBenchmark writes the average latency of every case on a line of its own.
+func printBenchmark(w io.Writer, iterations int, results []benchmarkResult) error {
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "%-10s %v average over %d iterations\n", r.name, r.average, iterations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	var calls []string
+	sleeper := func(name string, d time.Duration) benchmarkCase {
+		return benchmarkCase{name: name, run: func(context.Context) error {
+			calls = append(calls, name)
+			time.Sleep(d)
+			return nil
+		}}
+	}
+	results, err := runBenchmark(context.Background(), 3, []benchmarkCase{sleeper("sql", time.Millisecond), sleeper("read", 2*time.Millisecond)})
+	if err != nil {
+		t.Fatalf("runBenchmark: %v", err)
+	}
+	if got, want := strings.Join(calls, ","), "sql,read,sql,read,sql,read"; got != want {
+		t.Errorf("runBenchmark ran %s, want %s", got, want)
+	}
+	if len(results) != 2 || results[0].average < time.Millisecond || results[1].average < 2*time.Millisecond {
+		t.Errorf("runBenchmark = %+v, want averages of at least 1ms and 2ms", results)
+	}
+
+	var buf bytes.Buffer
+	if err := printBenchmark(&buf, 3, results); err != nil {
+		t.Fatalf("printBenchmark: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	latency := regexp.MustCompile(`^(sql|read) +[0-9.]+[µm]?s average over 3 iterations$`)
+	if len(lines) != 2 || !latency.MatchString(lines[0]) || !latency.MatchString(lines[1]) {
+		t.Errorf("printBenchmark wrote %q, want two latency lines", buf.String())
+	}
+}
+
+func TestRunBenchmarkErrors(t *testing.T) {
+	ok := benchmarkCase{name: "sql", run: func(context.Context) error { return nil }}
+	if _, err := runBenchmark(context.Background(), 0, []benchmarkCase{ok}); err == nil {
+		t.Error("runBenchmark with 0 iterations succeeded, want an error")
+	}
+	failing := benchmarkCase{name: "read", run: func(context.Context) error { return errors.New("injected failure") }}
+	if _, err := runBenchmark(context.Background(), 2, []benchmarkCase{ok, failing}); err == nil || !strings.Contains(err.Error(), "read: injected failure") {
+		t.Errorf("runBenchmark with a failing case = %v, want its error", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+// printDryRun writes the DDL statements and the mutations which run would execute to w,
+// without creating any client. It follows the same branches as runDatabase: a database
+// restored with --restore-from gets no DDL but the --ddl-file and no data, and one created
+// with --schema-only gets its schema but no data.
+func printDryRun(w io.Writer, opts spannerarrays.DatabaseOptions) error {
+	var ddl []string
+	if *restoreFrom != "" {
+		fmt.Fprintf(w, "-- restore %s from %s\n", *dsn, *restoreFrom)
+	} else {
+		var err error
+		if ddl, err = spannerarrays.DatabaseDDL(*dsn, opts); err != nil {
+			return err
+		}
+	}
+	if *ddlFile != "" {
+		b, err := ioutil.ReadFile(*ddlFile)
+		if err != nil {
+			return fmt.Errorf("failed to read DDL file: %v", err)
+		}
+		ddl = append(ddl, spannerarrays.ParseDDL(string(b))...)
+	}
+	if len(ddl) > 0 {
+		fmt.Fprintf(w, "-- DDL for %s\n", *dsn)
+		for _, stmt := range ddl {
+			fmt.Fprintf(w, "%s;\n", stmt)
+		}
+	}
+
+	// Like runDatabase, load nothing into a restored database or with --schema-only.
+	if *restoreFrom != "" || *schemaOnly {
+		fmt.Fprintln(w, "-- no data is loaded, nothing was executed")
+		return nil
+	}
+	mutations := spannerarrays.PresetMutations()
+	if *dataFile != "" {
+		var err error
+		if mutations, err = spannerarrays.FileMutations(*dataFile); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(w, "-- mutations")
+	for _, m := range mutations {
+		if *upsert && *dataFile == "" {
+			m.Op = "insert_or_update"
+		}
+		fmt.Fprintln(w, m)
+	}
+	fmt.Fprintf(w, "-- %d mutations, nothing was executed\n", len(mutations))
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+func TestRunDryRun(t *testing.T) {
+	defer func(old string, oldDryRun bool) { *dsn, *dryRun = old, oldDryRun }(*dsn, *dryRun)
+	*dsn, *dryRun = "projects/p/instances/i/databases/dry", true
+
+	var buf bytes.Buffer
+	defer func(old io.Writer) { stdout = old }(stdout)
+	stdout = &buf
+
+	defer func(oldAdmin func(context.Context, ...option.ClientOption) (*database.DatabaseAdminClient, error), oldClient func(context.Context, string, spanner.ClientConfig, ...option.ClientOption) (*spanner.Client, error)) {
+		newAdminClient, newClient = oldAdmin, oldClient
+	}(newAdminClient, newClient)
+	newAdminClient = func(context.Context, ...option.ClientOption) (*database.DatabaseAdminClient, error) {
+		t.Error("dry run created a database admin client")
+		return nil, errors.New("no clients in dry-run mode")
+	}
+	newClient = func(context.Context, string, spanner.ClientConfig, ...option.ClientOption) (*spanner.Client, error) {
+		t.Error("dry run created a data client")
+		return nil, errors.New("no clients in dry-run mode")
+	}
+
+	if err := run(context.Background()); err != nil {
+		t.Fatalf("run() in dry-run mode: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"CREATE DATABASE `dry`;",
+		"CREATE TABLE Countries (",
+		"CREATE INDEX CitiesByName ON Cities(Name);",
+		"insert Countries (49)",
+		"insert Cities (44,203)",
+		"-- 9 mutations, nothing was executed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dry run output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPrintDryRunSkipsData(t *testing.T) {
+	defer func(old string, oldSchema bool, oldRestore string) {
+		*dsn, *schemaOnly, *restoreFrom = old, oldSchema, oldRestore
+	}(*dsn, *schemaOnly, *restoreFrom)
+	*dsn = "projects/p/instances/i/databases/dry"
+
+	for _, tc := range []struct {
+		name       string
+		schemaOnly bool
+		restore    string
+		want       []string
+		notWant    []string
+	}{
+		{
+			name:       "schema-only",
+			schemaOnly: true,
+			want:       []string{"CREATE TABLE Countries (", "-- no data is loaded"},
+			notWant:    []string{"-- mutations", "insert Countries (49)"},
+		},
+		{
+			name:    "restore-from",
+			restore: "projects/p/instances/i/backups/b1",
+			want:    []string{"-- restore projects/p/instances/i/databases/dry from projects/p/instances/i/backups/b1", "-- no data is loaded"},
+			notWant: []string{"CREATE TABLE Countries (", "-- mutations"},
+		},
+	} {
+		*schemaOnly, *restoreFrom = tc.schemaOnly, tc.restore
+		var buf bytes.Buffer
+		if err := printDryRun(&buf, spannerarrays.DatabaseOptions{}); err != nil {
+			t.Errorf("%s: printDryRun: %v", tc.name, err)
+			continue
+		}
+		got := buf.String()
+		for _, want := range tc.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: dry run output does not contain %q:\n%s", tc.name, want, got)
+			}
+		}
+		for _, notWant := range tc.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("%s: dry run output contains %q, which the run skips:\n%s", tc.name, notWant, got)
+			}
+		}
+	}
+}
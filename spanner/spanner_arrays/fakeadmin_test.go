@@ -0,0 +1,77 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"testing"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// fakeAdmin is a database admin server which creates any database it is asked for, and
+// fails every backup with backupErr if it is set.
+type fakeAdmin struct {
+	adminpb.UnimplementedDatabaseAdminServer
+	t         *testing.T
+	backupErr error
+}
+
+func (f *fakeAdmin) GetDatabase(ctx context.Context, req *adminpb.GetDatabaseRequest) (*adminpb.Database, error) {
+	return nil, status.Errorf(codes.NotFound, "database %s not found", req.Name)
+}
+
+func (f *fakeAdmin) CreateDatabase(ctx context.Context, req *adminpb.CreateDatabaseRequest) (*longrunningpb.Operation, error) {
+	db := &adminpb.Database{Name: req.Parent + "/databases/created", State: adminpb.Database_READY}
+	a, err := anypb.New(db)
+	if err != nil {
+		f.t.Fatalf("anypb.New: %v", err)
+	}
+	return &longrunningpb.Operation{
+		Name:   db.Name + "/operations/1",
+		Done:   true,
+		Result: &longrunningpb.Operation_Response{Response: a},
+	}, nil
+}
+
+func (f *fakeAdmin) CreateBackup(ctx context.Context, req *adminpb.CreateBackupRequest) (*longrunningpb.Operation, error) {
+	if f.backupErr != nil {
+		return nil, f.backupErr
+	}
+	return nil, status.Error(codes.Unimplemented, "no backups")
+}
+
+// useFakeAdmin makes run connect to srv instead of the Spanner Admin API. It returns a
+// function which stops the server and restores newAdminClient.
+func useFakeAdmin(t *testing.T, srv adminpb.DatabaseAdminServer) func() {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	s := grpc.NewServer()
+	adminpb.RegisterDatabaseAdminServer(s, srv)
+	go s.Serve(lis)
+
+	old := newAdminClient
+	newAdminClient = func(ctx context.Context, opts ...option.ClientOption) (*database.DatabaseAdminClient, error) {
+		return database.NewDatabaseAdminClient(ctx,
+			option.WithEndpoint(lis.Addr().String()),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	}
+	return func() {
+		newAdminClient = old
+		s.Stop()
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// labelsFlag is a flag.Value collecting repeated key=value flags.
+type labelsFlag map[string]string
+
+// String returns the labels sorted by key, in the form a=1,b=2.
+func (l labelsFlag) String() string {
+	var pairs []string
+	for k, v := range l {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set adds one key=value label. The labels are validated when the database is created.
+func (l labelsFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid label %q, want key=value", s)
+	}
+	k, v := s[:i], s[i+1:]
+	if _, ok := l[k]; ok {
+		return fmt.Errorf("label %s given more than once", k)
+	}
+	l[k] = v
+	return nil
+}
@@ -0,0 +1,28 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestLabelsFlag(t *testing.T) {
+	l := labelsFlag{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(l, "label", "")
+	if err := fs.Parse([]string{"--label", "team=spanner", "--label=env=dev"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := l.String(), "env=dev,team=spanner"; got != want {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"team", "=x", "team=other"} {
+		if err := l.Set(bad); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", bad)
+		}
+	}
+}
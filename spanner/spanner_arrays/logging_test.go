@@ -0,0 +1,60 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerJSON(t *testing.T) {
+	var b bytes.Buffer
+	logger, err := newLogger(&b, "info", "json")
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	logger.Debug("hidden")
+	logger.Info("presets loaded", "database", "projects/p/instances/i/databases/d", "mutationCount", 9)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logger wrote %d lines, want 1 (debug records are below the info level): %q", len(lines), b.String())
+	}
+	var rec map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("json.Unmarshal(%q): %v", lines[0], err)
+	}
+	for _, key := range []string{"time", "level", "msg", "database", "mutationCount"} {
+		if _, ok := rec[key]; !ok {
+			t.Errorf("log record %q has no %q key", lines[0], key)
+		}
+	}
+	if rec["msg"] != "presets loaded" {
+		t.Errorf("msg = %v, want %q", rec["msg"], "presets loaded")
+	}
+}
+
+func TestNewLoggerText(t *testing.T) {
+	var b bytes.Buffer
+	logger, err := newLogger(&b, "debug", "text")
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	logger.Debug("database created", "database", "d")
+	if got := b.String(); !strings.Contains(got, "level=DEBUG") || !strings.Contains(got, "database=d") {
+		t.Errorf("text logger wrote %q, want a DEBUG record with database=d", got)
+	}
+}
+
+func TestNewLoggerInvalid(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, "verbose", "text"); err == nil {
+		t.Error("newLogger(level verbose) succeeded, want error")
+	}
+	if _, err := newLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("newLogger(format xml) succeeded, want error")
+	}
+}
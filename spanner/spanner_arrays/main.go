@@ -9,194 +9,892 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"log/slog"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/spanner"
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
 	"golang.org/x/net/context"
-	"google.golang.org/api/iterator"
-	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/api/option"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+var (
+	dsn                 = flag.String("database", placeholderDSN, "Cloud Spanner database name; a comma-separated list runs the sample against each database in turn; if unset, $"+databaseEnv+" is used")
+	format              = flag.String("format", "text", fmt.Sprintf("output format, one of: %s", strings.Join(formats, ", ")))
+	country             = flag.String("country", "", "only show the country with this name")
+	staleness           = flag.Duration("staleness", 0, "if non-zero, read data which may be up to this stale instead of doing a strong read")
+	dataFile            = flag.String("data", "", "JSON file with the countries and cities to load instead of the presets")
+	createAttempts      = flag.Int("create-attempts", 3, "number of attempts to create the database when it fails with a transient error")
+	createRetryDelay    = flag.Duration("create-retry-delay", time.Second, "pause after the first failed attempt to create the database; doubles after each further failure")
+	explain             = flag.Bool("explain", false, "print the query plan and execution statistics instead of the results")
+	timeout             = flag.Duration("timeout", 0, "if non-zero, the deadline for the whole run")
+	rpcTimeout          = flag.Duration("rpc-timeout", 0, "if non-zero, the deadline for each individual operation")
+	dropExisting        = flag.Bool("drop-existing", false, "drop the database first if it already exists, instead of reusing it")
+	traceExporter       = flag.String("trace-exporter", "none", "where to send OpenTelemetry trace spans, one of: stdout (one JSON object per span), none")
+	logLevel            = flag.String("log-level", "info", "minimum level of the log messages, one of: debug, info, warn, error")
+	logFormat           = flag.String("log-format", "text", "format of the log messages, text or json")
+	ddlFile             = flag.String("ddl-file", "", "file with semicolon-separated schema statements to apply after creating the database")
+	dialect             = flag.String("dialect", string(spannerarrays.GoogleSQL), "SQL dialect of the database, googlesql or postgresql")
+	createInstance      = flag.Bool("create-instance", false, "create the instance of --database first if it does not exist; it is kept afterwards")
+	instanceConfig      = flag.String("instance-config", "regional-us-central1", "instance configuration used by --create-instance")
+	instanceNodes       = flag.Int("instance-nodes", 1, "number of nodes of the instance created by --create-instance")
+	maxCommitDelay      = flag.Duration("max-commit-delay", 0, "if non-zero, let Spanner delay each commit of the load by up to this long to batch writes")
+	requestTag          = flag.String("request-tag", "", "if set, tag attached to the countries query in the query statistics")
+	transactionTag      = flag.String("transaction-tag", "", "if set, tag attached to the transactions which load the data or import --import-csv")
+	dryRun              = flag.Bool("dry-run", false, "only print the DDL and the mutations the sample would execute, without contacting Spanner")
+	upsert              = flag.Bool("upsert", false, "load the presets with insert-or-update mutations, so that an existing database can be reused")
+	minSessions         = flag.Uint64("min-sessions", spanner.DefaultSessionPoolConfig.MinOpened, "minimum number of sessions the client keeps open")
+	maxSessions         = flag.Uint64("max-sessions", spanner.DefaultSessionPoolConfig.MaxOpened, "maximum number of sessions the client opens")
+	writeSessions       = flag.Float64("write-sessions", spanner.DefaultSessionPoolConfig.WriteSessions, "fraction of the sessions prepared for read-write transactions, between 0 and 1")
+	readTimestamp       = flag.String("read-timestamp", "", "if set, an RFC3339 timestamp at which to read the data, e.g. 2006-01-02T15:04:05Z")
+	kmsKey              = flag.String("kms-key", "", "if set, the Cloud KMS key to encrypt the database with, projects/P/locations/L/keyRings/R/cryptoKeys/K")
+	leader              = flag.String("leader", "", "if set, the default leader region of the database in a multi-region instance")
+	backupID            = flag.String("backup", "", "if set, the ID of a backup of the database to create before dropping it")
+	backupRetention     = flag.Duration("backup-retention", 24*time.Hour, "how long the backup created by --backup is kept, between 6h and 366 days")
+	restoreFrom         = flag.String("restore-from", "", "if set, the backup projects/P/instances/I/backups/B to restore into --database instead of creating and loading it")
+	describe            = flag.Bool("describe", false, "print the columns of the Countries and Cities tables instead of loading and querying data")
+	concurrency         = flag.Int("concurrency", 0, "if positive, also query the cities of every country with a separate query, running this many at a time, and log how long both approaches took")
+	priority            = flag.String("priority", "", "if set, the priority of the query and of the commits loading the data or importing --import-csv: low, medium or high")
+	out                 = flag.String("out", "", "if set, also write the results to this file as newline-delimited JSON")
+	importCSV           = flag.String("import-csv", "", "if set, a CSV file with the columns CountryId,CityId,Name of cities to insert or update after loading the data")
+	skipPing            = flag.Bool("skip-ping", false, "do not check that the database can be queried before loading data")
+	orderCities         = flag.String("order-cities", "name", "order of the cities within each country: name or id")
+	incrementPopulation = flag.String("increment-population", "", "if set, COUNTRY_ID,CITY_ID,DELTA: add DELTA to the population of a city in a read-write transaction after loading the data")
+	maxRWAttempts       = flag.Int("max-rw-attempts", 0, "if positive, give up on a read-write transaction after it was aborted this many times instead of retrying until the timeout")
+	dsnProject          = flag.String("project", "", "Google Cloud project ID; together with --instance and --database-id, an alternative to --database")
+	dsnInstance         = flag.String("instance", "", "Cloud Spanner instance ID, see --project")
+	dsnDatabase         = flag.String("database-id", "", "Cloud Spanner database ID, see --project")
+	repl                = flag.Bool("repl", false, "after loading the data, read SQL statements from stdin and print their results until EOF or \\q")
+	keep                = flag.Bool("keep", false, "do not drop the database when the sample ends, so that it can be inspected afterwards")
+	metricsAddr         = flag.String("metrics-addr", "", "if set, serve the counters of rows read, mutations applied and queries run at http://ADDR/debug/vars")
+	sortBy              = flag.String("sort", string(sortNone), fmt.Sprintf("client-side order of the printed countries, one of: %s; name-ci sorts case-insensitively in Unicode collation order", strings.Join(sortModes, ", ")))
+	columns             = flag.String("columns", "", "if set, comma-separated columns of the countries to print as a table instead of the usual results, from: "+strings.Join(spannerarrays.CountryColumns, ", "))
+	verbose             = flag.Bool("verbose", false, "log how long each operation took, and the commit timestamps of the writes loading the data; implies --log-level=debug")
+	sqlFile             = flag.String("sql-file", "", "if set, a file with a single read-only SQL query to run after loading the data, whose results are printed as a table instead of the usual results")
+	benchmark           = flag.Bool("benchmark", false, "after loading the data, compare the average latency of reading the countries with the SQL query and with the Read API instead of printing them")
+	benchmarkIters      = flag.Int("benchmark-iterations", 10, "number of times --benchmark runs each approach")
+	labelOwner          = flag.String("label-owner", "", "if set, the label owner=VALUE, e.g. your username; Cloud Spanner databases can't carry labels, so it is only logged and recorded on the trace of the creation, not stored with the database")
+	cleanupOlderThan    = flag.Duration("cleanup-older-than", 0, "if non-zero, instead of running the sample, list the databases left behind by its tests (test-N) in the instance of --database which are older than this; add --confirm to drop them")
+	confirm             = flag.Bool("confirm", false, "actually drop the databases found by --cleanup-older-than")
+	noAdmin             = flag.Bool("no-admin", false, "query an existing, already populated --database without creating, loading or dropping it; no database admin client is opened")
+	loadConcurrency     = flag.Int("load-concurrency", 1, "number of batches of the data load committed at the same time; countries are always committed before cities")
+	watch               = flag.Duration("watch", 0, "if non-zero, after loading print the changes committed to the tables for this long")
+	queryRetries        = flag.Int("query-retries", 0, "number of times the countries query is retried when it fails with a transient error such as RESOURCE_EXHAUSTED")
+	deleteCity          = flag.String("delete-city", "", "if set, COUNTRY_ID,CITY_ID: soft-delete a city after loading the data, hiding it from the query")
+	hardDelete          = flag.Bool("hard-delete", false, "make --delete-city remove the row instead of marking it as deleted")
+	includeDeleted      = flag.Bool("include-deleted", false, "also show the soft-deleted cities")
+	limit               = flag.Int("limit", 0, "if positive, print at most this many countries, stopping the query once they have been read")
+	maxCities           = flag.Int64("max-cities", 0, "maximum number of cities to return per country, 0 for all")
+	schemaOnly          = flag.Bool("schema-only", false, "only create the database and its tables, without loading any data; combine with --keep to load your own data afterwards")
+	cache               = flag.Bool("cache", false, "cache the results of the country queries and of --repl in memory for --cache-ttl, e.g. to see the effect on --benchmark")
+	cacheTTL            = flag.Duration("cache-ttl", time.Minute, "how long --cache keeps a query result")
+	emulator            = flag.String("emulator", "", "address of a Cloud Spanner emulator, overrides $"+spannerarrays.EmulatorHostEnv)
 )
 
-// Country describes a country and the cities inside it.
-type Country struct {
-	Name    string
-	Colours []spanner.NullString
-	Cities  []spanner.NullString
+// labels are the --label flags, which may be repeated.
+var labels = labelsFlag{}
+
+func init() {
+	flag.Var(labels, "label", "key=value label describing the database, may be repeated; it is only logged and recorded on the trace of the creation, as Cloud Spanner databases can't carry labels")
 }
 
-func main() {
-	ctx := context.Background()
+// stdout is where run writes the query results.
+var stdout io.Writer = os.Stdout
+
+// stdin is where run reads the statements of --repl from.
+var stdin io.Reader = os.Stdin
+
+// newAdminClient and newClient create the clients used by run. Tests replace them to check
+// that no client is created.
+var (
+	newAdminClient = database.NewDatabaseAdminClient
+	newClient      = spanner.NewClientWithConfig
+)
+
+// queryRetryDelay is the pause before the first retry of the countries query; it doubles
+// after every further failure.
+const queryRetryDelay = 100 * time.Millisecond
+
+// cacheSize is the number of query results --cache holds at most.
+const cacheSize = 100
+
+// loadPresets populates the freshly created database. Tests replace it to inject failures.
+var loadPresets = spannerarrays.LoadPresets
 
-	dsn := flag.String("database", "projects/your-project-id/instances/your-instance-id/databases/your-database-id", "Cloud Spanner database name")
+// removeDatabase drops the database when the sample ends. Tests replace it to observe the
+// cleanup.
+var removeDatabase = spannerarrays.RemoveDatabase
+
+func main() {
 	flag.Parse()
+	dsnSet := false
+	flag.Visit(func(f *flag.Flag) { dsnSet = dsnSet || f.Name == "database" })
+	name, err := databaseName(*dsn, dsnSet, *dsnProject, *dsnInstance, *dsnDatabase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	*dsn = name
+
+	level := *logLevel
+	if *verbose {
+		level = "debug"
+	}
+	logger, err := newLogger(os.Stderr, level, *logFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	stopMetrics := func() {}
+	if *metricsAddr != "" {
+		var url string
+		url, stopMetrics, err = serveMetrics(*metricsAddr)
+		if err != nil {
+			log.Fatalf("failed to serve metrics: %v", err)
+		}
+		slog.Info("serving metrics", "url", url)
+	}
+
+	// run returns instead of exiting, so that its deferred cleanup (closing the clients and
+	// dropping the database) has finished before os.Exit is called, also when the sample is
+	// interrupted. os.Exit skips deferred calls, so the metrics server is stopped explicitly.
+	ctx, stop := shutdownContext(context.Background(), os.Stderr, shutdownSignals...)
+	err = run(ctx)
+	stop()
+	logMetrics()
+	stopMetrics()
+	if err != nil {
+		slog.Error("sample failed", "error", err)
+		os.Exit(1)
+	}
+}
 
-	// Connect to the Spanner Admin API.
-	admin, err := database.NewDatabaseAdminClient(ctx)
+// run runs the sample against every database in the comma-separated --database, one after
+// the other. With more than one database, the output of each is preceded by its name. The
+// run stops at the first database which fails, after that database has been cleaned up.
+func run(ctx context.Context) error {
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	dbs := strings.Split(*dsn, ",")
+	for i, db := range dbs {
+		dbs[i] = strings.TrimSpace(db)
+		if _, _, _, err := parseDatabaseName(dbs[i]); err != nil {
+			return err
+		}
+	}
+	shutdownTracing, err := setupTracing(*traceExporter, stdout)
 	if err != nil {
-		log.Fatalf("failed to create database admin client: %v", err)
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Warn("failed to shut down tracing", "error", err)
+		}
+	}()
+	if len(dbs) == 1 {
+		return runDatabase(ctx, dbs[0])
+	}
+	for _, db := range dbs {
+		if _, err := fmt.Fprintf(stdout, "== %s ==\n", db); err != nil {
+			return err
+		}
+		if err := runDatabase(ctx, db); err != nil {
+			return fmt.Errorf("%s: %v", db, err)
+		}
 	}
-	defer admin.Close()
+	return nil
+}
 
-	err = createDatabase(ctx, admin, *dsn)
+// runDatabase creates the demonstration database dsn, loads the presets, queries them and
+// finally drops the database again, even if one of the earlier steps failed.
+func runDatabase(ctx context.Context, dsn string) (err error) {
+	projectID, instanceID, databaseID, err := parseDatabaseName(dsn)
+	if err != nil {
+		return err
+	}
+	dbDialect, err := spannerarrays.ParseDialect(*dialect)
+	if err != nil {
+		return err
+	}
+	requestPriority, err := spannerarrays.ParsePriority(*priority)
+	if err != nil {
+		return err
+	}
+	cityOrder, err := spannerarrays.ParseCityOrder(*orderCities)
+	if err != nil {
+		return err
+	}
+	countryOrder, err := parseSortMode(*sortBy)
+	if err != nil {
+		return err
+	}
+	dbOptions := spannerarrays.DatabaseOptions{
+		Dialect:    dbDialect,
+		KMSKeyName: *kmsKey,
+		Leader:     *leader,
+		Labels:     labels,
+	}
+	if *labelOwner != "" {
+		if _, ok := labels["owner"]; ok {
+			return fmt.Errorf("--label-owner can't be combined with --label owner=...")
+		}
+		dbOptions.Labels = map[string]string{"owner": *labelOwner}
+		for k, v := range labels {
+			dbOptions.Labels[k] = v
+		}
+	}
+	var readAt time.Time
+	if *readTimestamp != "" {
+		if readAt, err = time.Parse(time.RFC3339Nano, *readTimestamp); err != nil {
+			return fmt.Errorf("invalid --read-timestamp, want an RFC3339 timestamp: %v", err)
+		}
+	}
+	var columnsSQL string
+	if *columns != "" {
+		if *country != "" {
+			return fmt.Errorf("--columns reads every country and can't be combined with --country")
+		}
+		if columnsSQL, err = spannerarrays.ColumnsSQL(strings.Split(*columns, ",")); err != nil {
+			return fmt.Errorf("invalid --columns: %v", err)
+		}
+	}
+	if *watch < 0 {
+		return fmt.Errorf("invalid --watch %v, must not be negative", *watch)
+	}
+	if *watch > 0 && dbDialect == spannerarrays.PostgreSQL {
+		return fmt.Errorf("--watch is only supported with --dialect %s", spannerarrays.GoogleSQL)
+	}
+	if *watch > 0 && *rpcTimeout > 0 && *watch > *rpcTimeout {
+		return fmt.Errorf("--watch %v is longer than --rpc-timeout %v", *watch, *rpcTimeout)
+	}
+	if _, err := parseColumnsOrder(*columnsOrder); err != nil {
+		return err
+	}
+	if *schemaOnly && (*restoreFrom != "" || *dataFile != "") {
+		return fmt.Errorf("--schema-only loads no data and can't be combined with --restore-from or --data")
+	}
+	if *hardDelete && *deleteCity == "" {
+		return fmt.Errorf("--hard-delete needs --delete-city")
+	}
+	if *queryRetries < 0 {
+		return fmt.Errorf("invalid --query-retries %d, must not be negative", *queryRetries)
+	}
+	if *loadConcurrency <= 0 {
+		return fmt.Errorf("invalid --load-concurrency %d, must be positive", *loadConcurrency)
+	}
+	spannerarrays.LoadConcurrency = *loadConcurrency
+	// queryCache is nil without --cache, which leaves the queries uncached.
+	var queryCache *spannerarrays.QueryCache
+	if *cache {
+		if queryCache, err = spannerarrays.NewQueryCache(cacheSize, *cacheTTL); err != nil {
+			return fmt.Errorf("invalid --cache-ttl: %v", err)
+		}
+	}
+	if *benchmark && *benchmarkIters <= 0 {
+		return fmt.Errorf("invalid --benchmark-iterations %d, must be positive", *benchmarkIters)
+	}
+	var fileSQL string
+	if *sqlFile != "" {
+		if fileSQL, err = readSQLFile(*sqlFile); err != nil {
+			return err
+		}
+	}
+	if *concurrency > 0 && *country != "" {
+		return fmt.Errorf("--concurrency reads every country and can't be combined with --country")
+	}
+	if *noAdmin {
+		if err := checkNoAdmin(); err != nil {
+			return err
+		}
+	}
+	if *dryRun {
+		return printDryRun(stdout, dbOptions)
+	}
+	opts := clientOptions()
+	config, err := clientConfig()
 	if err != nil {
-		log.Fatalf("failed to create database: %v", err)
+		return err
+	}
+
+	// With --no-admin the database already exists and is left alone, so only the data
+	// client is opened.
+	if !*noAdmin {
+		if *createInstance {
+			err = step(ctx, "failed to create instance", func(ctx context.Context) error {
+				return spannerarrays.CreateInstance(ctx, projectID, instanceID, *instanceConfig, int32(*instanceNodes), opts...)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// Connect to the Spanner Admin API. admin is assigned rather than declared, so that
+		// the deferred cleanup below reports its errors through the result err.
+		var admin *database.DatabaseAdminClient
+		admin, err = newAdminClient(ctx, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to create database admin client: %v", err)
+		}
+		defer admin.Close()
+
+		if *cleanupOlderThan > 0 {
+			return cleanupDatabases(ctx, admin, fmt.Sprintf("projects/%s/instances/%s", projectID, instanceID))
+		}
+		if *dropExisting {
+			err = step(ctx, "failed to drop existing database", func(ctx context.Context) error {
+				err := spannerarrays.RemoveDatabase(ctx, admin, dsn)
+				if status.Code(err) == codes.NotFound {
+					return nil
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if *restoreFrom != "" {
+			err = step(ctx, "failed to restore database", func(ctx context.Context) error {
+				return spannerarrays.RestoreDatabase(ctx, admin, "projects/"+projectID+"/instances/"+instanceID, databaseID, *restoreFrom)
+			})
+		} else {
+			err = step(ctx, "failed to create database", func(ctx context.Context) error {
+				return spannerarrays.CreateDatabaseWithRetry(ctx, admin, dsn, dbOptions, spannerarrays.RetryConfig{
+					MaxAttempts: *createAttempts,
+					BaseDelay:   *createRetryDelay,
+				})
+			})
+		}
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if *backupID != "" && err == nil {
+				err = step(ctx, "failed to back up database", func(ctx context.Context) error {
+					return spannerarrays.CreateBackup(ctx, admin, dsn, *backupID, time.Now().Add(*backupRetention))
+				})
+			}
+			if *keep {
+				slog.Info("keeping database, drop it once you are done with it", "database", dsn)
+				return
+			}
+			// Don't use ctx here: the database must be dropped even if the overall deadline
+			// has already expired.
+			rerr := step(context.Background(), "failed to remove database", func(ctx context.Context) error {
+				return removeDatabase(ctx, admin, dsn)
+			})
+			if rerr != nil {
+				slog.Error("failed to remove database", "database", dsn, "error", rerr)
+				if err == nil {
+					err = rerr
+				}
+				return
+			}
+			slog.Info("database removed", "database", dsn)
+		}()
+
+		if *ddlFile != "" {
+			ddl, err := ioutil.ReadFile(*ddlFile)
+			if err != nil {
+				return fmt.Errorf("failed to read DDL file: %v", err)
+			}
+			err = step(ctx, "failed to apply DDL file", func(ctx context.Context) error {
+				return spannerarrays.ApplyDDL(ctx, admin, dsn, spannerarrays.ParseDDL(string(ddl)))
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if *schemaOnly {
+			// The deferred function above still drops the database unless --keep is set.
+			slog.Info("schema created, skipping the data", "database", dsn)
+			return nil
+		}
 	}
-	defer removeDatabase(ctx, admin, *dsn)
 
 	// Connect to database.
-	client, err := spanner.NewClient(ctx, *dsn)
+	client, err := newClient(ctx, dsn, config, opts...)
 	if err != nil {
-		log.Fatalf("Failed to create client %v", err)
+		return fmt.Errorf("failed to create client: %v", err)
 	}
 	defer client.Close()
+	// The database only exists once it has been created, so this is the earliest point at
+	// which querying it can be checked.
+	if !*skipPing {
+		err = step(ctx, "failed to ping database", func(ctx context.Context) error {
+			return spannerarrays.Ping(ctx, client)
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	err = loadPresets(ctx, client)
-	if err != nil {
-		log.Fatalf("failed to load preset data: %v", err)
-	}
-
-	it := client.Single().Query(ctx, spanner.NewStatement(`
-		SELECT a.Name AS Name, ARRAY(
-			SELECT b.Name FROM Cities b WHERE a.CountryId = b.CountryId
-		) AS Cities, Colours FROM Countries a
-	`))
-	defer it.Stop()
-
-	for {
-		row, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Fatalf("failed to read results: %v", err)
-		}
-
-		var country Country
-		if err = row.ToStruct(&country); err != nil {
-			log.Fatalf("failed to read row into Country struct: %v", err)
-		}
-
-		var cities []string
-		for _, c := range country.Cities {
-			cities = append(cities, c.String())
-		}
-
-		var colours []string
-		for _, c := range country.Colours {
-			colours = append(colours, c.String())
-		}
-		log.Printf("%s (%s): %s", country.Name, strings.Join(colours, ", "), strings.Join(cities, ", "))
-	}
-}
-
-// loadPresets inserts some demonstration data into the tables.
-func loadPresets(ctx context.Context, db *spanner.Client) error {
-	mx := []*spanner.Mutation{
-		spanner.InsertMap("Countries", map[string]interface{}{
-			"CountryId": 49,
-			"Name":      "Germany",
-			"Colours":   []string{"black", "red", "gold"},
-		}),
-		spanner.InsertMap("Cities", map[string]interface{}{
-			"CountryId":  49,
-			"CityId":     100,
-			"Name":       "Berlin",
-			"Population": 3605000,
-		}),
-		spanner.InsertMap("Cities", map[string]interface{}{
-			"CountryId":  49,
-			"CityId":     101,
-			"Name":       "Hamburg",
-			"Population": 1739117,
-		}),
-		spanner.InsertMap("Cities", map[string]interface{}{
-			"CountryId":  49,
-			"CityId":     102,
-			"Name":       "Dresden",
-			"Population": 486854,
-		}),
-		spanner.InsertMap("Countries", map[string]interface{}{
-			"CountryId": 44,
-			"Name":      "United Kingdom",
-			"Colours":   []string{"white", "red", "blue"},
-		}),
-		spanner.InsertMap("Cities", map[string]interface{}{
-			"CountryId":  44,
-			"CityId":     200,
-			"Name":       "London",
-			"Population": 8788000,
-		}),
-		spanner.InsertMap("Cities", map[string]interface{}{
-			"CountryId":  44,
-			"CityId":     201,
-			"Name":       "Liverpool",
-			"Population": 465700,
-		}),
-		spanner.InsertMap("Cities", map[string]interface{}{
-			"CountryId":  44,
-			"CityId":     202,
-			"Name":       "Bristol",
-			"Population": 428100,
-		}),
-		spanner.InsertMap("Cities", map[string]interface{}{
-			"CountryId":  44,
-			"CityId":     203,
-			"Name":       "Newcastle",
-			"Population": 304636,
-		}),
-	}
-
-	_, err := db.Apply(ctx, mx)
-	return err
-}
-
-// createDatabase uses the Spanner database administration client to create the tables used in this demonstration.
-func createDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, db string) error {
-	matches := regexp.MustCompile("^(.*)/databases/(.*)$").FindStringSubmatch(db)
-	if matches == nil || len(matches) != 3 {
-		log.Fatalf("Invalid database id %s", db)
-	}
-
-	var (
-		projectID    = matches[1]
-		databaseName = matches[2]
-	)
-
-	op, err := adminClient.CreateDatabase(ctx, &adminpb.CreateDatabaseRequest{
-		Parent:          projectID,
-		CreateStatement: fmt.Sprintf("CREATE DATABASE `%s`", databaseName),
-		ExtraStatements: []string{
-			`CREATE TABLE Countries (
-				CountryId 	INT64 NOT NULL,
-				Name   		STRING(1024) NOT NULL,
-				Colours     ARRAY<STRING(1024)> NOT NULL
-			) PRIMARY KEY (CountryId)`,
-			`CREATE TABLE Cities (
-				CountryId	INT64 NOT NULL,
-				CityId		INT64 NOT NULL,
-				Name			STRING(MAX) NOT NULL,
-				Population  INT64 NOT NULL
-			) PRIMARY KEY (CountryId, CityId),
-			INTERLEAVE IN PARENT Countries ON DELETE CASCADE`,
+	if *describe {
+		var tables []spannerarrays.TableInfo
+		err = step(ctx, "failed to describe schema", func(ctx context.Context) error {
+			var err error
+			tables, err = spannerarrays.DescribeSchema(ctx, client)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return renderSchema(stdout, tables)
+	}
+
+	load := loadPresets
+	if *upsert {
+		load = spannerarrays.UpsertPresets
+	}
+	if *dataFile != "" {
+		load = func(ctx context.Context, client *spanner.Client, opts ...spanner.ApplyOption) error {
+			return spannerarrays.LoadFromFile(ctx, client, *dataFile, opts...)
+		}
+	}
+	// A restored database already contains the data of the backup, and the database of
+	// --no-admin its own.
+	if *restoreFrom == "" && !*noAdmin {
+		err = step(ctx, "failed to load data", func(ctx context.Context) error {
+			return load(ctx, client, applyOptions(requestPriority)...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *watch > 0 {
+		start := time.Now()
+		err = step(ctx, "failed to watch the change stream", func(ctx context.Context) error {
+			return spannerarrays.WatchChanges(ctx, client, start, start.Add(*watch), func(c spannerarrays.DataChange) {
+				fmt.Fprintln(stdout, c)
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *incrementPopulation != "" {
+		countryID, cityID, delta, err := parseIncrement(*incrementPopulation)
+		if err != nil {
+			return err
+		}
+		err = step(ctx, "failed to increment population", func(ctx context.Context) error {
+			return spannerarrays.IncrementPopulationWithMaxAttempts(ctx, client, countryID, cityID, delta, *maxRWAttempts)
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("population incremented", "country", countryID, "city", cityID, "delta", delta)
+	}
+	if *deleteCity != "" {
+		countryID, cityID, err := parseDeleteCity(*deleteCity)
+		if err != nil {
+			return err
+		}
+		del := spannerarrays.DeleteCity
+		if *hardDelete {
+			del = spannerarrays.HardDeleteCity
+		}
+		err = step(ctx, "failed to delete city", func(ctx context.Context) error {
+			return del(ctx, client, countryID, cityID)
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("city deleted", "country", countryID, "city", cityID, "hard", *hardDelete)
+	}
+	if *importCSV != "" {
+		var n int
+		err = step(ctx, "failed to import cities", func(ctx context.Context) error {
+			var err error
+			n, err = spannerarrays.ImportCitiesCSV(ctx, client, *importCSV, applyOptions(requestPriority)...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("cities imported", "file", *importCSV, "cities", n)
+	}
+
+	if *repl {
+		query := clientQuery(client)
+		if queryCache != nil {
+			query = cachedQuery(queryCache, client.DatabaseName(), query)
+		}
+		return runREPL(ctx, stdin, stdout, query)
+	}
+	if *benchmark {
+		var (
+			results         []benchmarkResult
+			sqlRes, readRes []spannerarrays.Country
+		)
+		err = step(ctx, "failed to run benchmark", func(ctx context.Context) error {
+			var err error
+			results, err = runBenchmark(ctx, *benchmarkIters, []benchmarkCase{
+				{name: "sql", run: func(ctx context.Context) (err error) {
+					sqlRes, err = spannerarrays.QueryCountriesWithConfig(ctx, client, spannerarrays.QueryConfig{Cache: queryCache})
+					return err
+				}},
+				{name: "read-api", run: func(ctx context.Context) (err error) {
+					readRes, err = spannerarrays.ReadCountries(ctx, client)
+					return err
+				}},
+			})
+			if err != nil {
+				return err
+			}
+			// The timings are only comparable if both read the same data.
+			if !sameCountries(sqlRes, readRes) {
+				return fmt.Errorf("the sql and read-api cases returned different countries")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return printBenchmark(stdout, *benchmarkIters, results)
+	}
+	if fileSQL != "" {
+		return step(ctx, "failed to run "+*sqlFile, func(ctx context.Context) error {
+			return printQuery(ctx, stdout, client, fileSQL)
+		})
+	}
+	if columnsSQL != "" {
+		var (
+			names []string
+			rows  [][]string
+		)
+		err = step(ctx, "failed to query columns", func(ctx context.Context) error {
+			var err error
+			names, rows, err = clientQuery(client)(ctx, columnsSQL)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return renderTable(stdout, names, rows)
+	}
+
+	cfg := spannerarrays.QueryConfig{
+		Name:           *country,
+		Staleness:      *staleness,
+		ReadTimestamp:  readAt,
+		Dialect:        dbDialect,
+		RequestTag:     *requestTag,
+		Priority:       requestPriority,
+		CityOrder:      cityOrder,
+		IncludeDeleted: *includeDeleted,
+		Limit:          *limit,
+		MaxCities:      *maxCities,
+		Cache:          queryCache,
+		Retry: spannerarrays.RetryConfig{
+			MaxAttempts: *queryRetries + 1,
+			BaseDelay:   queryRetryDelay,
+			Jitter:      true,
 		},
+	}
+	if *explain {
+		var profile *spannerarrays.QueryProfile
+		err = step(ctx, "failed to profile query", func(ctx context.Context) error {
+			var err error
+			profile, err = spannerarrays.ProfileCountries(ctx, client, cfg)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return renderProfile(stdout, profile)
+	}
+
+	var countries []spannerarrays.Country
+	start := time.Now()
+	err = step(ctx, "failed to query countries", func(ctx context.Context) error {
+		var err error
+		countries, err = spannerarrays.QueryCountriesWithConfig(ctx, client, cfg)
+		return err
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		return err
+	}
+	if *concurrency > 0 {
+		if err := compareConcurrent(ctx, client, countries, elapsed); err != nil {
+			return err
+		}
+	}
+	if *out != "" {
+		if err := spannerarrays.ExportCountries(*out, countries); err != nil {
+			return fmt.Errorf("failed to export results: %v", err)
+		}
+		slog.Info("results exported", "file", *out, "countries", len(countries))
+	}
+	if len(countries) == 0 && *country != "" {
+		slog.Info("no results: there is no country with this name", "country", *country)
+		return nil
+	}
+
+	sortCountries(countries, countryOrder)
+	if err := renderCountries(stdout, *format, countries); err != nil {
+		return fmt.Errorf("failed to render results: %v", err)
+	}
+	return nil
+}
+
+// compareConcurrent queries the countries again with one query per country, at most
+// --concurrency at a time, and logs how long that took compared to the single query, which
+// took elapsed and returned single, and whether both returned the same countries. Only the
+// countries of the single query are printed: the concurrent one reads every country with
+// the default query settings, so it differs from single if options such as --limit or
+// --max-cities are set.
+func compareConcurrent(ctx context.Context, client *spanner.Client, single []spannerarrays.Country, elapsed time.Duration) error {
+	var countries []spannerarrays.Country
+	start := time.Now()
+	err := step(ctx, "failed to query countries concurrently", func(ctx context.Context) error {
+		var err error
+		countries, err = spannerarrays.QueryCountriesConcurrently(ctx, client, *concurrency)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("compared query strategies",
+		"singleQuery", elapsed, "singleQueryCountries", len(single),
+		"concurrent", time.Since(start), "concurrentCountries", len(countries), "concurrency", *concurrency,
+		"sameResults", sameCountries(single, countries))
+	return nil
+}
+
+// sameCountries reports whether a and b hold the same countries with the same cities, in
+// any order of the countries.
+func sameCountries(a, b []spannerarrays.Country) bool {
+	summaries := func(countries []spannerarrays.Country) string {
+		var s []string
+		for _, c := range countries {
+			s = append(s, c.String())
+		}
+		sort.Strings(s)
+		return strings.Join(s, "\n")
+	}
+	return summaries(a) == summaries(b)
+}
+
+// checkNoAdmin returns an error if a flag which needs the database admin client is
+// combined with --no-admin.
+func checkNoAdmin() error {
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"create-instance", *createInstance},
+		{"drop-existing", *dropExisting},
+		{"restore-from", *restoreFrom != ""},
+		{"backup", *backupID != ""},
+		{"ddl-file", *ddlFile != ""},
+		{"cleanup-older-than", *cleanupOlderThan > 0},
+		{"dry-run", *dryRun},
+		{"schema-only", *schemaOnly},
+	} {
+		if f.set {
+			return fmt.Errorf("--%s needs the database admin client and can't be combined with --no-admin", f.name)
+		}
+	}
+	return nil
+}
+
+// cleanupDatabases drops the databases of instance left behind by the tests which are older
+// than --cleanup-older-than, or only logs them without --confirm.
+func cleanupDatabases(ctx context.Context, admin *database.DatabaseAdminClient, instance string) error {
+	if !*confirm {
+		var names []string
+		err := step(ctx, "failed to find old sample databases", func(ctx context.Context) error {
+			var err error
+			names, err = spannerarrays.OldSampleDatabases(ctx, admin, instance, *cleanupOlderThan)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, db := range names {
+			slog.Info("would drop old sample database", "database", db)
+		}
+		slog.Info("nothing dropped: pass --confirm to drop the old sample databases", "databases", len(names))
+		return nil
+	}
+
+	var n int
+	err := step(ctx, "failed to drop old sample databases", func(ctx context.Context) error {
+		var err error
+		n, err = spannerarrays.CleanupOldSampleDatabases(ctx, admin, instance, *cleanupOlderThan)
+		return err
 	})
 	if err != nil {
 		return err
 	}
-	if _, err := op.Wait(ctx); err == nil {
-		log.Printf("Created database [%s]", db)
+	slog.Info("old sample databases dropped", "instance", instance, "databases", n)
+	return nil
+}
+
+// step runs one operation of the sample under the --rpc-timeout deadline. If it fails,
+// the returned error is prefixed with desc and says which deadline, if any, was exceeded.
+// With --verbose, the time the operation took is logged, named after desc without its
+// "failed to" prefix.
+func step(ctx context.Context, desc string, f func(context.Context) error) error {
+	rctx, cancel := ctx, context.CancelFunc(func() {})
+	if *rpcTimeout > 0 {
+		rctx, cancel = context.WithTimeout(ctx, *rpcTimeout)
+	}
+	defer cancel()
+
+	start := time.Now()
+	err := f(rctx)
+	if *verbose {
+		slog.InfoContext(ctx, "operation finished", "operation", strings.TrimPrefix(desc, "failed to "), "elapsed", time.Since(start), "ok", err == nil)
+	}
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("%s: overall timeout of %v exceeded: %v", desc, *timeout, err)
+	case rctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("%s: per-RPC timeout of %v exceeded: %v", desc, *rpcTimeout, err)
+	}
+	return fmt.Errorf("%s: %v", desc, err)
+}
+
+// applyOptions returns the options of the commits which load the data, which run with
+// priority p.
+func applyOptions(p sppb.RequestOptions_Priority) []spanner.ApplyOption {
+	opts := spannerarrays.CommitDelayOptions(*maxCommitDelay)
+	if *transactionTag != "" {
+		opts = append(opts, spanner.TransactionTag(*transactionTag))
+	}
+	if p != sppb.RequestOptions_PRIORITY_UNSPECIFIED {
+		opts = append(opts, spanner.Priority(p))
+	}
+	return opts
+}
+
+// databaseNameRE matches a fully qualified database name and captures its project, instance
+// and database IDs.
+var databaseNameRE = regexp.MustCompile("^projects/([^/]+)/instances/([^/]+)/databases/([^/]+)$")
+
+// buildDSN returns the fully qualified name of the database in the given project and instance.
+func buildDSN(project, instance, database string) string {
+	return fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, database)
+}
+
+// placeholderDSN is the default of --database. It names no real database, so it is treated
+// as if --database had not been given.
+const placeholderDSN = "projects/your-project-id/instances/your-instance-id/databases/your-database-id"
+
+// databaseEnv is the environment variable naming the database when no flag does, which is
+// convenient in containers.
+const databaseEnv = "SPANNER_DATABASE"
+
+// databaseName returns the name of the database the sample uses: dsn, the value of
+// --database, or the name assembled from the --project, --instance and --database-id values
+// project, instance and database. dsnSet reports whether --database was given explicitly;
+// the two forms can't be combined. If neither names a database, the value of $SPANNER_DATABASE
+// is used, and it is an error if that is empty too.
+func databaseName(dsn string, dsnSet bool, project, instance, database string) (string, error) {
+	if project == "" && instance == "" && database == "" {
+		if dsn != placeholderDSN {
+			return dsn, nil
+		}
+		if env := os.Getenv(databaseEnv); env != "" {
+			return env, nil
+		}
+		return "", fmt.Errorf("no database given, set --database, --project, --instance and --database-id, or $%s", databaseEnv)
+	}
+	if dsnSet {
+		return "", fmt.Errorf("--database can't be combined with --project, --instance and --database-id")
+	}
+	if project == "" || instance == "" || database == "" {
+		return "", fmt.Errorf("--project, --instance and --database-id must be given together")
 	}
-	return err
+	return buildDSN(project, instance, database), nil
 }
 
-// removeDatabase deletes the database which this demonstration program created.
-func removeDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, db string) {
-	if err := adminClient.DropDatabase(ctx, &adminpb.DropDatabaseRequest{Database: db}); err != nil {
-		log.Fatalf("Failed to remove database [%s]: %v", db, err)
-	} else {
-		log.Printf("Removed database [%s]", db)
+// parseDatabaseName splits the database name dsn into its project, instance and database IDs.
+func parseDatabaseName(dsn string) (project, instance, database string, err error) {
+	matches := databaseNameRE.FindStringSubmatch(dsn)
+	if matches == nil {
+		return "", "", "", fmt.Errorf("invalid database name %q, want projects/P/instances/I/databases/D", dsn)
+	}
+	return matches[1], matches[2], matches[3], nil
+}
+
+// parseIncrement parses the --increment-population value COUNTRY_ID,CITY_ID,DELTA.
+func parseIncrement(s string) (countryID, cityID, delta int64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("invalid --increment-population %q, want COUNTRY_ID,CITY_ID,DELTA", s)
+	}
+	var n [3]int64
+	for i, p := range parts {
+		if n[i], err = strconv.ParseInt(strings.TrimSpace(p), 10, 64); err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid --increment-population %q, want COUNTRY_ID,CITY_ID,DELTA: %v", s, err)
+		}
+	}
+	return n[0], n[1], n[2], nil
+}
+
+// parseDeleteCity parses the COUNTRY_ID,CITY_ID value of --delete-city.
+func parseDeleteCity(s string) (countryID, cityID int64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid --delete-city %q, want COUNTRY_ID,CITY_ID", s)
+	}
+	var n [2]int64
+	for i, p := range parts {
+		if n[i], err = strconv.ParseInt(strings.TrimSpace(p), 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid --delete-city %q, want COUNTRY_ID,CITY_ID: %v", s, err)
+		}
+	}
+	return n[0], n[1], nil
+}
+
+// clientConfig returns the configuration of the data client, with the session pool sized by
+// --min-sessions, --max-sessions and --write-sessions.
+func clientConfig() (spanner.ClientConfig, error) {
+	if *maxSessions < *minSessions {
+		return spanner.ClientConfig{}, fmt.Errorf("--max-sessions (%d) must not be smaller than --min-sessions (%d)", *maxSessions, *minSessions)
+	}
+	if *writeSessions < 0 || *writeSessions > 1 {
+		return spanner.ClientConfig{}, fmt.Errorf("--write-sessions (%v) must be between 0 and 1", *writeSessions)
+	}
+	pool := spanner.DefaultSessionPoolConfig
+	pool.MinOpened = *minSessions
+	pool.MaxOpened = *maxSessions
+	pool.WriteSessions = *writeSessions
+	return spanner.ClientConfig{SessionPoolConfig: pool}, nil
+}
+
+// clientOptions returns the options used for both the admin and the data client.
+// When SPANNER_EMULATOR_HOST (or --emulator) is set, both clients talk to the local
+// emulator over an insecure connection and without credentials, instead of to Cloud Spanner.
+func clientOptions() []option.ClientOption {
+	emulatorHost := os.Getenv(spannerarrays.EmulatorHostEnv)
+	if *emulator != "" {
+		emulatorHost = *emulator
+	}
+	if emulatorHost == "" {
+		return nil
 	}
+	slog.Info("using Cloud Spanner emulator", "host", emulatorHost)
+	return spannerarrays.EmulatorOptions(emulatorHost)
 }
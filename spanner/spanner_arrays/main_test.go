@@ -0,0 +1,534 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+// The project and instance testDSN creates on the emulator, like newTestClient in the
+// spannerarrays tests.
+const (
+	emulatorProject  = "test-project"
+	emulatorInstance = "test-instance"
+	emulatorConfig   = "emulator-config"
+)
+
+// testDSN points --database at a new, uniquely named database for the duration of a test.
+// It returns a function which restores the previous flag value. With SPANNER_EMULATOR_HOST
+// set, the database is on that emulator, whose instance is created first when needed;
+// otherwise in the instance GOLANG_SAMPLES_SPANNER names. The test is skipped when neither
+// is set.
+func testDSN(t *testing.T) func() {
+	instance := os.Getenv("GOLANG_SAMPLES_SPANNER")
+	if host := os.Getenv(spannerarrays.EmulatorHostEnv); host != "" {
+		if err := spannerarrays.CreateInstance(context.Background(), emulatorProject, emulatorInstance, emulatorConfig, 1, spannerarrays.EmulatorOptions(host)...); err != nil {
+			t.Fatalf("CreateInstance on emulator %s: %v", host, err)
+		}
+		instance = fmt.Sprintf("projects/%s/instances/%s", emulatorProject, emulatorInstance)
+	}
+	if instance == "" {
+		t.Skip("Skipping spanner integration test. Set SPANNER_EMULATOR_HOST or GOLANG_SAMPLES_SPANNER.")
+	}
+	old := *dsn
+	*dsn = fmt.Sprintf("%s/databases/test-%d", instance, time.Now().UnixNano())
+	return func() { *dsn = old }
+}
+
+func TestRunDropsDatabaseOnFailure(t *testing.T) {
+	defer testDSN(t)()
+	ctx := context.Background()
+
+	injected := errors.New("injected failure")
+	defer func(old func(context.Context, *spanner.Client, ...spanner.ApplyOption) error) { loadPresets = old }(loadPresets)
+	loadPresets = func(context.Context, *spanner.Client, ...spanner.ApplyOption) error { return injected }
+
+	if err := run(ctx); err == nil || !strings.Contains(err.Error(), injected.Error()) {
+		t.Fatalf("run() = %v, want the injected failure", err)
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, clientOptions()...)
+	if err != nil {
+		t.Fatalf("NewDatabaseAdminClient: %v", err)
+	}
+	defer admin.Close()
+	_, err = admin.GetDatabase(ctx, &adminpb.GetDatabaseRequest{Name: *dsn})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetDatabase(%q) after failed run: got err %v, want NotFound", *dsn, err)
+	}
+}
+
+func TestRunKeep(t *testing.T) {
+	defer testDSN(t)()
+	ctx := context.Background()
+
+	defer func(old bool) { *keep = old }(*keep)
+	*keep = true
+	defer func(old func(context.Context, *database.DatabaseAdminClient, string) error) { removeDatabase = old }(removeDatabase)
+	removeDatabase = func(context.Context, *database.DatabaseAdminClient, string) error {
+		t.Error("run with --keep dropped the database")
+		return nil
+	}
+
+	if err := run(ctx); err != nil {
+		t.Fatalf("run() with --keep: %v", err)
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, clientOptions()...)
+	if err != nil {
+		t.Fatalf("NewDatabaseAdminClient: %v", err)
+	}
+	defer admin.Close()
+	if _, err := admin.GetDatabase(ctx, &adminpb.GetDatabaseRequest{Name: *dsn}); err != nil {
+		t.Errorf("GetDatabase(%q) after run with --keep: %v", *dsn, err)
+	}
+	if err := spannerarrays.RemoveDatabase(ctx, admin, *dsn); err != nil {
+		t.Errorf("RemoveDatabase(%q): %v", *dsn, err)
+	}
+}
+
+func TestRunSchemaOnly(t *testing.T) {
+	ctx := context.Background()
+	defer func(old bool) { *schemaOnly = old }(*schemaOnly)
+	*schemaOnly = true
+
+	// The conflicting flags are rejected before anything is created.
+	func() {
+		defer func(old, oldData string) { *dsn, *dataFile = old, oldData }(*dsn, *dataFile)
+		*dsn, *dataFile = "projects/p/instances/i/databases/d", "countries.json"
+		err := run(ctx)
+		if err == nil || !strings.Contains(err.Error(), "--restore-from or --data") {
+			t.Errorf("run() with --schema-only and --data = %v, want an error naming --restore-from and --data", err)
+		}
+	}()
+
+	defer testDSN(t)()
+	defer func(old bool) { *keep = old }(*keep)
+	*keep = true
+
+	if err := run(ctx); err != nil {
+		t.Fatalf("run() with --schema-only: %v", err)
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, clientOptions()...)
+	if err != nil {
+		t.Fatalf("NewDatabaseAdminClient: %v", err)
+	}
+	defer admin.Close()
+	defer func() {
+		if err := spannerarrays.RemoveDatabase(ctx, admin, *dsn); err != nil {
+			t.Errorf("RemoveDatabase(%q): %v", *dsn, err)
+		}
+	}()
+
+	client, err := spanner.NewClient(ctx, *dsn, clientOptions()...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	for _, table := range []string{"Countries", "Cities"} {
+		var n int64
+		err := client.Single().Query(ctx, spanner.NewStatement("SELECT COUNT(*) FROM "+table)).Do(func(row *spanner.Row) error {
+			return row.Column(0, &n)
+		})
+		if err != nil {
+			t.Fatalf("counting the rows of %s: %v", table, err)
+		}
+		if n != 0 {
+			t.Errorf("%s has %d rows after run with --schema-only, want 0", table, n)
+		}
+	}
+}
+
+func TestRunCountryInjection(t *testing.T) {
+	defer testDSN(t)()
+	const name = "O'Brien; DROP TABLE Countries"
+
+	defer func(old func(context.Context, *spanner.Client, ...spanner.ApplyOption) error) { loadPresets = old }(loadPresets)
+	loadPresets = func(ctx context.Context, client *spanner.Client, opts ...spanner.ApplyOption) error {
+		if err := spannerarrays.LoadPresets(ctx, client, opts...); err != nil {
+			return err
+		}
+		return spannerarrays.InsertCountry(ctx, client, 353, name)
+	}
+	defer func(old string) { *country = old }(*country)
+	*country = name
+	var buf bytes.Buffer
+	defer func(old io.Writer) { stdout = old }(stdout)
+	stdout = &buf
+
+	if err := run(context.Background()); err != nil {
+		t.Fatalf("run() with --country %q: %v", name, err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, name) {
+		t.Errorf("output of --country %q doesn't contain the country:\n%s", name, out)
+	}
+	if strings.Contains(out, "Germany") {
+		t.Errorf("output of --country %q contains other countries:\n%s", name, out)
+	}
+}
+
+func TestRunReportsCleanupErrors(t *testing.T) {
+	defer func(old string) { *dsn = old }(*dsn)
+	*dsn = "projects/p/instances/i/databases/cleanup"
+	// --schema-only stops right after creating the database, so no data client is needed.
+	defer func(old bool) { *schemaOnly = old }(*schemaOnly)
+	*schemaOnly = true
+
+	dropFailed := errors.New("injected drop failure")
+	defer func(old func(context.Context, *database.DatabaseAdminClient, string) error) { removeDatabase = old }(removeDatabase)
+	removeDatabase = func(context.Context, *database.DatabaseAdminClient, string) error { return dropFailed }
+
+	fake := &fakeAdmin{t: t}
+	defer useFakeAdmin(t, fake)()
+	if err := run(context.Background()); err == nil || !strings.Contains(err.Error(), dropFailed.Error()) {
+		t.Errorf("run() with a failing drop = %v, want the drop failure", err)
+	}
+
+	removeDatabase = func(context.Context, *database.DatabaseAdminClient, string) error { return nil }
+	fake.backupErr = status.Error(codes.PermissionDenied, "injected backup failure")
+	defer func(old string) { *backupID = old }(*backupID)
+	*backupID = "b1"
+	if err := run(context.Background()); err == nil || !strings.Contains(err.Error(), "injected backup failure") {
+		t.Errorf("run() with a failing --backup = %v, want the backup failure", err)
+	}
+}
+
+func TestRunMultipleDatabases(t *testing.T) {
+	defer testDSN(t)()
+	ctx := context.Background()
+	first, second := *dsn, *dsn+"-2"
+	*dsn = first + "," + second
+
+	var buf bytes.Buffer
+	defer func(old io.Writer) { stdout = old }(stdout)
+	stdout = &buf
+
+	if err := run(ctx); err != nil {
+		t.Fatalf("run() with two databases: %v", err)
+	}
+	out := buf.String()
+	i, j := strings.Index(out, "== "+first+" =="), strings.Index(out, "== "+second+" ==")
+	if i < 0 || j < i {
+		t.Fatalf("output doesn't name %s and then %s:\n%s", first, second, out)
+	}
+	if !strings.Contains(out[i:j], "Germany") || !strings.Contains(out[j:], "Germany") {
+		t.Errorf("output doesn't contain the results of both databases:\n%s", out)
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, clientOptions()...)
+	if err != nil {
+		t.Fatalf("NewDatabaseAdminClient: %v", err)
+	}
+	defer admin.Close()
+	for _, db := range []string{first, second} {
+		if _, err := admin.GetDatabase(ctx, &adminpb.GetDatabaseRequest{Name: db}); status.Code(err) != codes.NotFound {
+			t.Errorf("GetDatabase(%q) after run: got err %v, want NotFound", db, err)
+		}
+	}
+}
+
+func TestRunNoAdmin(t *testing.T) {
+	defer func(old string, oldNoAdmin bool) { *dsn, *noAdmin = old, oldNoAdmin }(*dsn, *noAdmin)
+	*dsn, *noAdmin = "projects/p/instances/i/databases/existing", true
+
+	defer func(oldAdmin func(context.Context, ...option.ClientOption) (*database.DatabaseAdminClient, error), oldClient func(context.Context, string, spanner.ClientConfig, ...option.ClientOption) (*spanner.Client, error)) {
+		newAdminClient, newClient = oldAdmin, oldClient
+	}(newAdminClient, newClient)
+	newAdminClient = func(context.Context, ...option.ClientOption) (*database.DatabaseAdminClient, error) {
+		t.Error("run with --no-admin created a database admin client")
+		return nil, errors.New("no admin client with --no-admin")
+	}
+	// There is no database to connect to, so stop once run gets to the data client.
+	connected := errors.New("connected to the data client")
+	var opened string
+	newClient = func(ctx context.Context, db string, config spanner.ClientConfig, opts ...option.ClientOption) (*spanner.Client, error) {
+		opened = db
+		return nil, connected
+	}
+	defer func(old func(context.Context, *spanner.Client, ...spanner.ApplyOption) error) { loadPresets = old }(loadPresets)
+	loadPresets = func(context.Context, *spanner.Client, ...spanner.ApplyOption) error {
+		t.Error("run with --no-admin loaded the presets")
+		return nil
+	}
+
+	if err := run(context.Background()); err == nil || !strings.Contains(err.Error(), connected.Error()) {
+		t.Fatalf("run() with --no-admin = %v, want it to reach the data client", err)
+	}
+	if opened != *dsn {
+		t.Errorf("run() with --no-admin connected to %q, want %q", opened, *dsn)
+	}
+
+	defer func(old bool) { *dropExisting = old }(*dropExisting)
+	*dropExisting = true
+	if err := run(context.Background()); err == nil || !strings.Contains(err.Error(), "can't be combined with --no-admin") {
+		t.Errorf("run() with --no-admin and --drop-existing = %v, want a conflicting flags error", err)
+	}
+}
+
+func TestSameCountries(t *testing.T) {
+	city := func(name string) spanner.NullString { return spanner.NullString{StringVal: name, Valid: true} }
+	germany := spannerarrays.Country{Name: "Germany", Cities: []spanner.NullString{city("Berlin"), city("Dresden")}}
+	uk := spannerarrays.Country{Name: "United Kingdom", Cities: []spanner.NullString{city("London")}}
+	if !sameCountries([]spannerarrays.Country{germany, uk}, []spannerarrays.Country{uk, germany}) {
+		t.Error("sameCountries of the same countries in another order = false, want true")
+	}
+	fewer := spannerarrays.Country{Name: "Germany", Cities: []spanner.NullString{city("Berlin")}}
+	if sameCountries([]spannerarrays.Country{germany, uk}, []spannerarrays.Country{fewer, uk}) {
+		t.Error("sameCountries of countries with different cities = true, want false")
+	}
+}
+
+func TestStepTimeouts(t *testing.T) {
+	defer func(old time.Duration) { *rpcTimeout = old }(*rpcTimeout)
+	*rpcTimeout = time.Millisecond
+	wait := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err := step(context.Background(), "waiting", wait)
+	if err == nil || !strings.Contains(err.Error(), "per-RPC timeout") {
+		t.Errorf("step with expired --rpc-timeout = %v, want per-RPC timeout error", err)
+	}
+
+	*rpcTimeout = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err = step(ctx, "waiting", wait)
+	if err == nil || !strings.Contains(err.Error(), "overall timeout") {
+		t.Errorf("step with expired overall deadline = %v, want overall timeout error", err)
+	}
+
+	if err := step(context.Background(), "succeeding", func(context.Context) error { return nil }); err != nil {
+		t.Errorf("step(succeeding) = %v, want nil", err)
+	}
+}
+
+func TestStepVerbose(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(old *slog.Logger) { slog.SetDefault(old) }(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer func(old bool) { *verbose = old }(*verbose)
+
+	*verbose = false
+	step(context.Background(), "failed to load data", func(context.Context) error { return nil })
+	if buf.Len() != 0 {
+		t.Errorf("step without --verbose logged %q, want nothing", buf.String())
+	}
+
+	*verbose = true
+	step(context.Background(), "failed to load data", func(context.Context) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if logged := buf.String(); !strings.Contains(logged, `operation="load data"`) || !strings.Contains(logged, "elapsed=") {
+		t.Errorf("step with --verbose logged %q, want the latency of load data", logged)
+	}
+}
+
+func TestRunVerbose(t *testing.T) {
+	defer testDSN(t)()
+	var buf bytes.Buffer
+	defer func(old *slog.Logger) { slog.SetDefault(old) }(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer func(old bool) { *verbose = old }(*verbose)
+	*verbose = true
+
+	if err := run(context.Background()); err != nil {
+		t.Fatalf("run() with --verbose: %v", err)
+	}
+	logged := buf.String()
+	for _, want := range []string{`operation="load data"`, "commit_timestamp="} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("run() with --verbose didn't log %q:\n%s", want, logged)
+		}
+	}
+}
+
+func TestRunTimeouts(t *testing.T) {
+	defer testDSN(t)()
+	defer func(old, oldRPC time.Duration) { *timeout, *rpcTimeout = old, oldRPC }(*timeout, *rpcTimeout)
+
+	*timeout, *rpcTimeout = time.Nanosecond, 0
+	if err := run(context.Background()); err == nil || !strings.Contains(err.Error(), "overall timeout") {
+		t.Errorf("run with --timeout=1ns = %v, want overall timeout error", err)
+	}
+
+	*timeout, *rpcTimeout = 0, time.Nanosecond
+	if err := run(context.Background()); err == nil || !strings.Contains(err.Error(), "per-RPC timeout") {
+		t.Errorf("run with --rpc-timeout=1ns = %v, want per-RPC timeout error", err)
+	}
+}
+
+func TestParseDatabaseName(t *testing.T) {
+	project, instance, db, err := parseDatabaseName("projects/my-project/instances/my-instance/databases/my-db")
+	if err != nil {
+		t.Fatalf("parseDatabaseName(valid name): %v", err)
+	}
+	if project != "my-project" || instance != "my-instance" || db != "my-db" {
+		t.Errorf("parseDatabaseName = %q, %q, %q, want my-project, my-instance, my-db", project, instance, db)
+	}
+
+	for _, dsn := range []string{
+		"",
+		"my-db",
+		"projects/p/instances/i",
+		"projects/p/instances/i/databases/",
+		"projects//instances/i/databases/d",
+		"projects/p/instances/i/databases/d/tables/t",
+		"project/p/instances/i/databases/d",
+		"/projects/p/instances/i/databases/d",
+	} {
+		if _, _, _, err := parseDatabaseName(dsn); err == nil || !strings.Contains(err.Error(), "want projects/P/instances/I/databases/D") {
+			t.Errorf("parseDatabaseName(%q) = %v, want an invalid database name error", dsn, err)
+		}
+	}
+}
+
+func TestBuildDSN(t *testing.T) {
+	if got, want := buildDSN("my-project", "my-instance", "my-db"), "projects/my-project/instances/my-instance/databases/my-db"; got != want {
+		t.Errorf("buildDSN = %q, want %q", got, want)
+	}
+}
+
+func TestDatabaseName(t *testing.T) {
+	const dsn = "projects/p/instances/i/databases/d"
+	for _, tc := range []struct {
+		desc                    string
+		dsnSet                  bool
+		project, instance, dbID string
+		want                    string
+	}{
+		{desc: "--database", dsnSet: true, want: dsn},
+		{desc: "default --database", want: dsn},
+		{desc: "separate flags", project: "p2", instance: "i2", dbID: "d2", want: "projects/p2/instances/i2/databases/d2"},
+	} {
+		got, err := databaseName(dsn, tc.dsnSet, tc.project, tc.instance, tc.dbID)
+		if err != nil || got != tc.want {
+			t.Errorf("databaseName(%s) = %q, %v, want %q", tc.desc, got, err, tc.want)
+		}
+	}
+
+	if _, err := databaseName(dsn, true, "p2", "i2", "d2"); err == nil || !strings.Contains(err.Error(), "can't be combined") {
+		t.Errorf("databaseName with --database and the separate flags = %v, want a conflicting flags error", err)
+	}
+	if _, err := databaseName(dsn, false, "p2", "", "d2"); err == nil || !strings.Contains(err.Error(), "must be given together") {
+		t.Errorf("databaseName without --instance = %v, want an incomplete flags error", err)
+	}
+}
+
+func TestDatabaseNameFromEnv(t *testing.T) {
+	const env = "projects/p/instances/i/databases/from-env"
+	t.Setenv(databaseEnv, env)
+
+	// The placeholder default of --database counts as unset, even when given explicitly.
+	for _, dsnSet := range []bool{false, true} {
+		if got, err := databaseName(placeholderDSN, dsnSet, "", "", ""); err != nil || got != env {
+			t.Errorf("databaseName(placeholder, dsnSet=%v) with $%s = %q, %v, want %q", dsnSet, databaseEnv, got, err, env)
+		}
+	}
+	// Flags take precedence over the environment.
+	if got, err := databaseName("projects/p/instances/i/databases/flag", true, "", "", ""); err != nil || got != "projects/p/instances/i/databases/flag" {
+		t.Errorf("databaseName(--database) with $%s = %q, %v, want the flag's database", databaseEnv, got, err)
+	}
+	if got, err := databaseName(placeholderDSN, false, "p2", "i2", "d2"); err != nil || got != "projects/p2/instances/i2/databases/d2" {
+		t.Errorf("databaseName(separate flags) with $%s = %q, %v, want the flags' database", databaseEnv, got, err)
+	}
+
+	t.Setenv(databaseEnv, "")
+	if _, err := databaseName(placeholderDSN, false, "", "", ""); err == nil || !strings.Contains(err.Error(), databaseEnv) {
+		t.Errorf("databaseName without any database = %v, want an error mentioning $%s", err, databaseEnv)
+	}
+}
+
+func TestParseIncrement(t *testing.T) {
+	country, city, delta, err := parseIncrement("49, 102,-5")
+	if err != nil {
+		t.Fatalf("parseIncrement: %v", err)
+	}
+	if country != 49 || city != 102 || delta != -5 {
+		t.Errorf("parseIncrement(49, 102,-5) = %d, %d, %d, want 49, 102, -5", country, city, delta)
+	}
+	for _, s := range []string{"", "49,102", "49,102,5,1", "49,Berlin,5"} {
+		if _, _, _, err := parseIncrement(s); err == nil {
+			t.Errorf("parseIncrement(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestParseDeleteCity(t *testing.T) {
+	country, city, err := parseDeleteCity("49, 102")
+	if err != nil {
+		t.Fatalf("parseDeleteCity: %v", err)
+	}
+	if country != 49 || city != 102 {
+		t.Errorf("parseDeleteCity(49, 102) = %d, %d, want 49, 102", country, city)
+	}
+	for _, s := range []string{"", "49", "49,102,5", "49,Dresden"} {
+		if _, _, err := parseDeleteCity(s); err == nil {
+			t.Errorf("parseDeleteCity(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestApplyOptions(t *testing.T) {
+	defer func(delay time.Duration, tag string) { *maxCommitDelay, *transactionTag = delay, tag }(*maxCommitDelay, *transactionTag)
+
+	*maxCommitDelay, *transactionTag = 0, ""
+	if opts := applyOptions(sppb.RequestOptions_PRIORITY_UNSPECIFIED); len(opts) != 0 {
+		t.Errorf("applyOptions() without delay, tag and priority = %d options, want none", len(opts))
+	}
+	*maxCommitDelay, *transactionTag = time.Millisecond, "app=test"
+	if opts := applyOptions(sppb.RequestOptions_PRIORITY_UNSPECIFIED); len(opts) != 2 {
+		t.Errorf("applyOptions() with delay and tag = %d options, want 2", len(opts))
+	}
+	if opts := applyOptions(sppb.RequestOptions_PRIORITY_LOW); len(opts) != 3 {
+		t.Errorf("applyOptions() with delay, tag and priority = %d options, want 3", len(opts))
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	defer func(min, max uint64, write float64) { *minSessions, *maxSessions, *writeSessions = min, max, write }(*minSessions, *maxSessions, *writeSessions)
+
+	*minSessions, *maxSessions, *writeSessions = 5, 50, 0.5
+	config, err := clientConfig()
+	if err != nil {
+		t.Fatalf("clientConfig(): %v", err)
+	}
+	pool := config.SessionPoolConfig
+	if pool.MinOpened != 5 || pool.MaxOpened != 50 || pool.WriteSessions != 0.5 {
+		t.Errorf("session pool = min %d, max %d, write %v, want 5, 50, 0.5", pool.MinOpened, pool.MaxOpened, pool.WriteSessions)
+	}
+
+	*minSessions, *maxSessions = 50, 5
+	if _, err := clientConfig(); err == nil {
+		t.Error("clientConfig() with --max-sessions < --min-sessions succeeded, want an error")
+	}
+	*minSessions, *maxSessions, *writeSessions = 5, 50, 1.5
+	if _, err := clientConfig(); err == nil {
+		t.Error("clientConfig() with --write-sessions 1.5 succeeded, want an error")
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	// Registers the /debug/vars handler, which serves the spannerarrays counters.
+	_ "expvar"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+// serveMetrics serves the expvar counters at http://addr/debug/vars until the returned
+// function is called. It returns the URL of the counters, which contains the port chosen by
+// the system if addr has port 0.
+func serveMetrics(addr string) (url string, stop func(), err error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", nil, err
+	}
+	go http.Serve(l, http.DefaultServeMux)
+	return "http://" + l.Addr().String() + "/debug/vars", func() { l.Close() }, nil
+}
+
+// logMetrics logs the final values of the spannerarrays counters.
+func logMetrics() {
+	slog.Info("metrics",
+		"rowsRead", spannerarrays.RowsRead.Value(),
+		"mutationsApplied", spannerarrays.MutationsApplied.Value(),
+		"queriesRun", spannerarrays.QueriesRun.Value())
+}
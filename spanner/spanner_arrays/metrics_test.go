@@ -0,0 +1,34 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestServeMetrics(t *testing.T) {
+	url, stop, err := serveMetrics("localhost:0")
+	if err != nil {
+		t.Fatalf("serveMetrics: %v", err)
+	}
+	defer stop()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	var vars map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatalf("parsing %s: %v", url, err)
+	}
+	for _, name := range []string{"spannerarrays.rows_read", "spannerarrays.mutations_applied", "spannerarrays.queries_run"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("%s does not contain %s", url, name)
+		}
+	}
+}
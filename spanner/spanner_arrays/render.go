@@ -0,0 +1,257 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"cloud.google.com/go/spanner"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+// formats lists the values accepted by the --format flag.
+var formats = []string{"text", "table", "json", "csv"}
+
+// nullText is displayed in place of NULL array elements.
+var nullText = flag.String("null-text", "<null>", "text shown for NULL city names")
+
+// tableWidth limits the width of the cities column of the table format.
+var tableWidth = flag.Int("table-width", 0, "if positive, the maximum width of the cities column of --format table; longer city lists are cut short")
+
+// outputColumns are the fields of the json and csv formats, in their default order.
+var outputColumns = []string{"Name", "Cities"}
+
+// columnsOrder reorders the fields of the json and csv formats.
+var columnsOrder = flag.String("columns-order", "", fmt.Sprintf("comma-separated order of the fields of --format json and csv, listing each of: %s", strings.Join(outputColumns, ", ")))
+
+// renderCountries writes countries to w in the given output format.
+func renderCountries(w io.Writer, format string, countries []spannerarrays.Country) error {
+	order, err := parseColumnsOrder(*columnsOrder)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "text":
+		for _, country := range countries {
+			colours := strings.Join(nullStringsToDisplay(country.Colours), ", ")
+			cities := strings.Join(nullStringsToDisplay(country.Cities), ", ")
+			if _, err := fmt.Fprintf(w, "%s (%s): %s\n", country.Name, colours, cities); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "COUNTRY\tCOLOURS\tCITIES")
+		for _, country := range countries {
+			colours := strings.Join(nullStringsToDisplay(country.Colours), ", ")
+			cities := truncateList(nullStringsToDisplay(country.Cities), *tableWidth)
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", country.Name, colours, cities)
+		}
+		return tw.Flush()
+
+	case "json":
+		out := []jsonObject{}
+		for _, country := range countries {
+			fields := map[string]interface{}{"Name": country.Name, "Cities": nullStringsToDisplay(country.Cities)}
+			var obj jsonObject
+			for _, c := range order {
+				obj = append(obj, jsonField{name: c, value: fields[c]})
+			}
+			out = append(out, obj)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := map[string]string{"Name": "Country", "Cities": "City"}
+		record := make([]string, len(order))
+		for i, c := range order {
+			record[i] = header[c]
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		for _, country := range countries {
+			for _, city := range nullStringsToDisplay(country.Cities) {
+				fields := map[string]string{"Name": country.Name, "Cities": city}
+				for i, c := range order {
+					record[i] = fields[c]
+				}
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("invalid format %q, want one of: %s", format, strings.Join(formats, ", "))
+}
+
+// parseColumnsOrder returns the order of the fields given by the --columns-order value s,
+// which must list every one of outputColumns exactly once, ignoring case. An empty s means
+// the default order.
+func parseColumnsOrder(s string) ([]string, error) {
+	if s == "" {
+		return outputColumns, nil
+	}
+	var order []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		var column string
+		for _, c := range outputColumns {
+			if strings.EqualFold(c, name) {
+				column = c
+			}
+		}
+		if column == "" {
+			return nil, fmt.Errorf("invalid --columns-order: unknown column %q, valid columns are: %s", name, strings.Join(outputColumns, ", "))
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("invalid --columns-order: column %s is listed twice", column)
+		}
+		seen[column] = true
+		order = append(order, column)
+	}
+	if len(order) != len(outputColumns) {
+		return nil, fmt.Errorf("invalid --columns-order %q: it must list each of %s", s, strings.Join(outputColumns, ", "))
+	}
+	return order, nil
+}
+
+// jsonObject is a JSON object whose fields are written in order, unlike those of a map.
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// truncateList joins items with commas. If the result is wider than width characters and
+// width is positive, it keeps as many leading items as fit together with an ellipsis and the
+// number of items left out, as in "Berlin, Hamburg, ... (+1 more)".
+func truncateList(items []string, width int) string {
+	full := strings.Join(items, ", ")
+	if width <= 0 || utf8.RuneCountInString(full) <= width {
+		return full
+	}
+	for n := len(items) - 1; n > 0; n-- {
+		s := fmt.Sprintf("%s, ... (+%d more)", strings.Join(items[:n], ", "), len(items)-n)
+		if utf8.RuneCountInString(s) <= width {
+			return s
+		}
+	}
+	return fmt.Sprintf("... (+%d more)", len(items))
+}
+
+// nullStringsToDisplay converts a Spanner string array into plain strings for display,
+// substituting the --null-text placeholder for NULL elements.
+func nullStringsToDisplay(cities []spanner.NullString) []string {
+	out := make([]string, 0, len(cities))
+	for _, c := range cities {
+		if !c.Valid {
+			out = append(out, *nullText)
+			continue
+		}
+		out = append(out, c.StringVal)
+	}
+	return out
+}
+
+// renderProfile writes the relational operators of a query plan as an indented tree,
+// followed by the query statistics.
+func renderProfile(w io.Writer, p *spannerarrays.QueryProfile) error {
+	if p.Plan != nil && len(p.Plan.PlanNodes) > 0 {
+		fmt.Fprintln(w, "Query plan:")
+		renderPlanNode(w, p.Plan.PlanNodes, 0, 1)
+	}
+	fmt.Fprintln(w, "Query stats:")
+	var keys []string
+	for k := range p.Stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "  %s: %v\n", k, p.Stats[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderPlanNode writes nodes[index] and its relational descendants, indented by depth.
+// Scalar nodes, which describe expressions rather than operators, are left out.
+func renderPlanNode(w io.Writer, nodes []*sppb.PlanNode, index int32, depth int) {
+	if index < 0 || int(index) >= len(nodes) {
+		return
+	}
+	node := nodes[index]
+	fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", depth), node.DisplayName)
+	for _, link := range node.ChildLinks {
+		if child := link.ChildIndex; int(child) < len(nodes) && nodes[child].Kind == sppb.PlanNode_RELATIONAL {
+			renderPlanNode(w, nodes, child, depth+1)
+		}
+	}
+}
+
+// renderSchema writes the name, type and nullability of the columns of every table, with
+// the columns of each table aligned.
+func renderSchema(w io.Writer, tables []spannerarrays.TableInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for i, table := range tables {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s\n", table.Name)
+		fmt.Fprintln(tw, "  COLUMN\tTYPE\tNULLABLE")
+		for _, c := range table.Columns {
+			nullable := "NO"
+			if c.Nullable {
+				nullable = "YES"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", c.Name, c.Type, nullable)
+		}
+	}
+	return tw.Flush()
+}
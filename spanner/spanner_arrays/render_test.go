@@ -0,0 +1,229 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+var testCountries = []spannerarrays.Country{
+	{
+		Name:    "Germany",
+		Colours: []spanner.NullString{{StringVal: "black", Valid: true}, {StringVal: "red", Valid: true}},
+		Cities:  []spanner.NullString{{StringVal: "Berlin", Valid: true}, {StringVal: "Hamburg", Valid: true}},
+	},
+	{
+		Name:    "United Kingdom",
+		Colours: []spanner.NullString{{StringVal: "white", Valid: true}},
+		Cities:  []spanner.NullString{{StringVal: "London", Valid: true}},
+	},
+}
+
+func TestRenderCountries(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "text",
+			want:   "Germany (black, red): Berlin, Hamburg\nUnited Kingdom (white): London\n",
+		},
+		{
+			format: "csv",
+			want:   "Country,City\nGermany,Berlin\nGermany,Hamburg\nUnited Kingdom,London\n",
+		},
+		{
+			format: "table",
+			want: "COUNTRY         COLOURS     CITIES\n" +
+				"Germany         black, red  Berlin, Hamburg\n" +
+				"United Kingdom  white       London\n",
+		},
+	}
+	for _, tc := range tests {
+		var b bytes.Buffer
+		if err := renderCountries(&b, tc.format, testCountries); err != nil {
+			t.Errorf("renderCountries(%q): %v", tc.format, err)
+			continue
+		}
+		if got := b.String(); got != tc.want {
+			t.Errorf("renderCountries(%q) = %q, want %q", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestTruncateList(t *testing.T) {
+	cities := []string{"Berlin", "Hamburg", "Dresden", "Munich"}
+	for _, tc := range []struct {
+		width int
+		want  string
+	}{
+		{width: 0, want: "Berlin, Hamburg, Dresden, Munich"},
+		{width: 32, want: "Berlin, Hamburg, Dresden, Munich"},
+		{width: 31, want: "Berlin, Hamburg, ... (+2 more)"},
+		{width: 30, want: "Berlin, Hamburg, ... (+2 more)"},
+		{width: 29, want: "Berlin, ... (+3 more)"},
+		{width: 20, want: "... (+4 more)"},
+	} {
+		if got := truncateList(cities, tc.width); got != tc.want {
+			t.Errorf("truncateList(%q, %d) = %q, want %q", cities, tc.width, got, tc.want)
+		}
+	}
+}
+
+func TestRenderCountriesTableWidth(t *testing.T) {
+	defer func(old int) { *tableWidth = old }(*tableWidth)
+	*tableWidth = 10
+
+	var b bytes.Buffer
+	if err := renderCountries(&b, "table", testCountries); err != nil {
+		t.Fatalf("renderCountries(table): %v", err)
+	}
+	want := "COUNTRY         COLOURS     CITIES\n" +
+		"Germany         black, red  ... (+2 more)\n" +
+		"United Kingdom  white       London\n"
+	if got := b.String(); got != want {
+		t.Errorf("renderCountries(table) with --table-width 10 = %q, want %q", got, want)
+	}
+}
+
+func TestRenderCountriesJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := renderCountries(&b, "json", testCountries); err != nil {
+		t.Fatalf("renderCountries(json): %v", err)
+	}
+	var got []struct {
+		Name   string
+		Cities []string
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%q): %v", b.String(), err)
+	}
+	if len(got) != 2 || got[0].Name != "Germany" || strings.Join(got[0].Cities, ",") != "Berlin,Hamburg" {
+		t.Errorf("renderCountries(json) = %q, want Germany with Berlin and Hamburg first", b.String())
+	}
+}
+
+func TestRenderCountriesColumnsOrder(t *testing.T) {
+	defer func(old string) { *columnsOrder = old }(*columnsOrder)
+	*columnsOrder = "cities, Name"
+
+	var b bytes.Buffer
+	if err := renderCountries(&b, "csv", testCountries); err != nil {
+		t.Fatalf("renderCountries(csv): %v", err)
+	}
+	if got, want := b.String(), "City,Country\nBerlin,Germany\nHamburg,Germany\nLondon,United Kingdom\n"; got != want {
+		t.Errorf("renderCountries(csv) with --columns-order %q = %q, want %q", *columnsOrder, got, want)
+	}
+
+	b.Reset()
+	if err := renderCountries(&b, "json", testCountries[:1]); err != nil {
+		t.Fatalf("renderCountries(json): %v", err)
+	}
+	if got, want := strings.Join(strings.Fields(b.String()), ""), `[{"Cities":["Berlin","Hamburg"],"Name":"Germany"}]`; got != want {
+		t.Errorf("renderCountries(json) with --columns-order %q = %q, want %q", *columnsOrder, got, want)
+	}
+
+	for _, order := range []string{"Cities,Population", "Name", "Name,Cities,Name"} {
+		*columnsOrder = order
+		if err := renderCountries(&b, "csv", testCountries); err == nil {
+			t.Errorf("renderCountries with --columns-order %q succeeded, want an error", order)
+		}
+	}
+}
+
+func TestRenderCountriesInvalidFormat(t *testing.T) {
+	err := renderCountries(&bytes.Buffer{}, "xml", testCountries)
+	if err == nil {
+		t.Fatal("renderCountries(xml) succeeded, want error")
+	}
+	for _, f := range formats {
+		if !strings.Contains(err.Error(), f) {
+			t.Errorf("error %q does not mention valid format %q", err, f)
+		}
+	}
+}
+
+func TestRenderNullCity(t *testing.T) {
+	countries := []spannerarrays.Country{{
+		Name:    "Germany",
+		Colours: []spanner.NullString{{StringVal: "black", Valid: true}},
+		Cities:  []spanner.NullString{{StringVal: "Berlin", Valid: true}, {}},
+	}}
+
+	var b bytes.Buffer
+	if err := renderCountries(&b, "text", countries); err != nil {
+		t.Fatalf("renderCountries: %v", err)
+	}
+	if got, want := b.String(), "Germany (black): Berlin, <null>\n"; got != want {
+		t.Errorf("renderCountries with NULL city = %q, want %q", got, want)
+	}
+
+	defer func(old string) { *nullText = old }(*nullText)
+	*nullText = "?"
+	b.Reset()
+	if err := renderCountries(&b, "text", countries); err != nil {
+		t.Fatalf("renderCountries: %v", err)
+	}
+	if got, want := b.String(), "Germany (black): Berlin, ?\n"; got != want {
+		t.Errorf("renderCountries with --null-text=? = %q, want %q", got, want)
+	}
+}
+
+func TestRenderProfile(t *testing.T) {
+	p := &spannerarrays.QueryProfile{
+		Plan: &sppb.QueryPlan{PlanNodes: []*sppb.PlanNode{
+			{Index: 0, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Distributed Union", ChildLinks: []*sppb.PlanNode_ChildLink{{ChildIndex: 1}, {ChildIndex: 3}}},
+			{Index: 1, Kind: sppb.PlanNode_RELATIONAL, DisplayName: "Table Scan", ChildLinks: []*sppb.PlanNode_ChildLink{{ChildIndex: 2}}},
+			{Index: 2, Kind: sppb.PlanNode_SCALAR, DisplayName: "Reference"},
+			{Index: 3, Kind: sppb.PlanNode_SCALAR, DisplayName: "Constant"},
+		}},
+		Stats: map[string]interface{}{"rows_scanned": "9", "elapsed_time": "1.2 msecs"},
+	}
+
+	var b bytes.Buffer
+	if err := renderProfile(&b, p); err != nil {
+		t.Fatalf("renderProfile: %v", err)
+	}
+	want := "Query plan:\n  Distributed Union\n    Table Scan\nQuery stats:\n  elapsed_time: 1.2 msecs\n  rows_scanned: 9\n"
+	if got := b.String(); got != want {
+		t.Errorf("renderProfile = %q, want %q", got, want)
+	}
+}
+
+func TestRenderSchema(t *testing.T) {
+	tables := []spannerarrays.TableInfo{
+		{Name: "Countries", Columns: []spannerarrays.ColumnInfo{
+			{Name: "CountryId", Type: "INT64"},
+			{Name: "Colours", Type: "ARRAY<STRING(1024)>"},
+		}},
+		{Name: "Cities", Columns: []spannerarrays.ColumnInfo{
+			{Name: "Name", Type: "STRING(MAX)", Nullable: true},
+		}},
+	}
+
+	var b bytes.Buffer
+	if err := renderSchema(&b, tables); err != nil {
+		t.Fatalf("renderSchema: %v", err)
+	}
+	want := "Countries\n" +
+		"  COLUMN     TYPE                 NULLABLE\n" +
+		"  CountryId  INT64                NO\n" +
+		"  Colours    ARRAY<STRING(1024)>  NO\n" +
+		"\n" +
+		"Cities\n" +
+		"  COLUMN  TYPE         NULLABLE\n" +
+		"  Name    STRING(MAX)  YES\n"
+	if got := b.String(); got != want {
+		t.Errorf("renderSchema = %q, want %q", got, want)
+	}
+}
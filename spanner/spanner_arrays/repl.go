@@ -0,0 +1,281 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+// replPrompt is written before reading each statement in --repl mode.
+const replPrompt = "spanner> "
+
+// replQuery runs the SQL statement sql and returns the names of its columns and its rows,
+// formatted for display.
+type replQuery func(ctx context.Context, sql string) (columns []string, rows [][]string, err error)
+
+// runREPL reads SQL statements from r, one per line, runs each of them with query and
+// writes the results to w as a table, until r is exhausted or the statement \q is read.
+// A failing statement is reported on w and doesn't end the loop.
+func runREPL(ctx context.Context, r io.Reader, w io.Writer, query replQuery) error {
+	s := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, replPrompt)
+		if !s.Scan() {
+			fmt.Fprintln(w)
+			return s.Err()
+		}
+		sql := strings.TrimSuffix(strings.TrimSpace(s.Text()), ";")
+		switch sql {
+		case "":
+			continue
+		case `\q`:
+			return nil
+		}
+
+		columns, rows, err := query(ctx, sql)
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		if err := renderTable(w, columns, rows); err != nil {
+			return err
+		}
+	}
+}
+
+// readSQLFile returns the query in the file at path, without surrounding space and a
+// trailing semicolon.
+func readSQLFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read --sql-file: %v", err)
+	}
+	sql := strings.TrimSuffix(strings.TrimSpace(string(b)), ";")
+	if strings.TrimSpace(sql) == "" {
+		return "", fmt.Errorf("--sql-file %s contains no query", path)
+	}
+	return sql, nil
+}
+
+// renderTable writes rows under a header of columns, followed by the number of rows.
+func renderTable(w io.Writer, columns []string, rows [][]string) error {
+	if len(columns) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "(%d rows)\n", len(rows))
+	return err
+}
+
+// clientQuery returns a replQuery which runs statements with a single-use read-only
+// transaction of client, so the REPL can only read.
+func clientQuery(client *spanner.Client) replQuery {
+	return func(ctx context.Context, sql string) ([]string, [][]string, error) {
+		it := client.Single().Query(ctx, spanner.NewStatement(sql))
+		defer it.Stop()
+
+		var (
+			columns []string
+			rows    [][]string
+		)
+		for {
+			row, err := it.Next()
+			if err == iterator.Done {
+				return columns, rows, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			columns = row.ColumnNames()
+			values, err := rowValues(row)
+			if err != nil {
+				return nil, nil, err
+			}
+			rows = append(rows, values)
+		}
+	}
+}
+
+// replResult is the result of a replQuery, as held by cachedQuery.
+type replResult struct {
+	columns []string
+	rows    [][]string
+}
+
+// cachedQuery returns a replQuery which runs the statements with query in the database db,
+// but answers the statements found in cache from it instead.
+func cachedQuery(cache *spannerarrays.QueryCache, db string, query replQuery) replQuery {
+	return func(ctx context.Context, sql string) ([]string, [][]string, error) {
+		key := "repl\x00" + sql
+		if v, ok := cache.Get(db, key); ok {
+			r := v.(replResult)
+			return r.columns, r.rows, nil
+		}
+		columns, rows, err := query(ctx, sql)
+		if err != nil {
+			return nil, nil, err
+		}
+		cache.Put(db, key, replResult{columns: columns, rows: rows})
+		return columns, rows, nil
+	}
+}
+
+// printQuery runs sql with a single-use read-only transaction of client and writes the
+// results to w like renderTable, but prints each row as soon as it is read instead of
+// collecting them first, so that it can be used for queries returning many rows.
+func printQuery(ctx context.Context, w io.Writer, client *spanner.Client, sql string) error {
+	it := client.Single().Query(ctx, spanner.NewStatement(sql))
+	defer it.Stop()
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	n := 0
+	for ; ; n++ {
+		row, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			fmt.Fprintln(tw, strings.Join(row.ColumnNames(), "\t"))
+		}
+		if err := printRow(tw, row); err != nil {
+			return err
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "(%d rows)\n", n)
+	return err
+}
+
+// printRow writes the values of the columns of row to w, separated by tabs and followed by
+// a newline, for a tabwriter to align them.
+func printRow(w io.Writer, row *spanner.Row) error {
+	values, err := rowValues(row)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, strings.Join(values, "\t"))
+	return err
+}
+
+// rowValues formats the values of the columns of row for display, whatever their type.
+func rowValues(row *spanner.Row) ([]string, error) {
+	values := make([]string, row.Size())
+	for i := range values {
+		var v spanner.GenericColumnValue
+		if err := row.Column(i, &v); err != nil {
+			return nil, fmt.Errorf("failed to decode column %s: %v", row.ColumnName(i), err)
+		}
+		values[i] = formatColumn(v.Type, v.Value)
+	}
+	return values, nil
+}
+
+// formatColumn formats the value v of a column of type t for display:
+//   - NULL as NULL, whatever the type;
+//   - BOOL as true or false;
+//   - FLOAT64 in the shortest representation, or as NaN, Infinity or -Infinity;
+//   - INT64, STRING, DATE, TIMESTAMP and JSON as sent by Spanner, which encodes them all as
+//     strings, so that no precision is lost;
+//   - NUMERIC by formatNumeric;
+//   - BYTES as base64, which is how Spanner encodes them too;
+//   - ARRAY as its elements in brackets, formatted by their type.
+//
+// Values of types it doesn't know about are formatted by formatValue.
+func formatColumn(t *sppb.Type, v *structpb.Value) string {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); v == nil || ok {
+		return "NULL"
+	}
+	switch t.GetCode() {
+	case sppb.TypeCode_BOOL:
+		return strconv.FormatBool(v.GetBoolValue())
+	case sppb.TypeCode_FLOAT64:
+		if s, ok := v.GetKind().(*structpb.Value_StringValue); ok {
+			return s.StringValue
+		}
+		return strconv.FormatFloat(v.GetNumberValue(), 'g', -1, 64)
+	case sppb.TypeCode_NUMERIC:
+		return formatNumeric(t, v)
+	case sppb.TypeCode_INT64, sppb.TypeCode_STRING, sppb.TypeCode_DATE,
+		sppb.TypeCode_TIMESTAMP, sppb.TypeCode_JSON, sppb.TypeCode_BYTES:
+		return v.GetStringValue()
+	case sppb.TypeCode_ARRAY:
+		var elems []string
+		for _, e := range v.GetListValue().GetValues() {
+			elems = append(elems, formatColumn(t.GetArrayElementType(), e))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	return formatValue(v)
+}
+
+// numericScale is the number of digits after the decimal point of a GoogleSQL NUMERIC.
+// Spanner has no separate BIGNUMERIC type; NUMERIC already holds 38 digits of precision.
+const numericScale = 9
+
+// formatNumeric formats the NUMERIC value v of type t in decimal notation with every digit
+// of its scale, without the trailing zeros, e.g. 4072191736000.123456789. The value is
+// decoded into a spanner.NullNumeric rather than a float64, which would round it to about
+// 16 significant digits. PostgreSQL numeric values have no fixed scale and may be NaN, so
+// they are shown as sent by Spanner.
+func formatNumeric(t *sppb.Type, v *structpb.Value) string {
+	if t.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_NUMERIC {
+		return v.GetStringValue()
+	}
+	var n spanner.NullNumeric
+	if err := (spanner.GenericColumnValue{Type: t, Value: v}).Decode(&n); err != nil || !n.Valid {
+		return formatValue(v)
+	}
+	s := n.Numeric.FloatString(numericScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// formatValue formats a column value of any type for display. Spanner encodes INT64,
+// NUMERIC, TIMESTAMP, DATE and BYTES values as strings, so they are shown as sent.
+func formatValue(v *structpb.Value) string {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return k.StringValue
+	case *structpb.Value_NumberValue:
+		return strconv.FormatFloat(k.NumberValue, 'g', -1, 64)
+	case *structpb.Value_BoolValue:
+		return strconv.FormatBool(k.BoolValue)
+	case *structpb.Value_ListValue:
+		var elems []string
+		for _, e := range k.ListValue.GetValues() {
+			elems = append(elems, formatValue(e))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	return "NULL"
+}
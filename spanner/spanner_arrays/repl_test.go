@@ -0,0 +1,264 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+func TestRunREPL(t *testing.T) {
+	var queries []string
+	query := func(ctx context.Context, sql string) ([]string, [][]string, error) {
+		queries = append(queries, sql)
+		if strings.HasPrefix(sql, "SELEC ") {
+			return nil, nil, errors.New("syntax error")
+		}
+		return []string{"Name", "CityCount"}, [][]string{{"Germany", "3"}, {"United Kingdom", "4"}}, nil
+	}
+	in := "SELECT Name, CityCount FROM Counts;\n\nSELEC oops\nSELECT again\n\\q\nSELECT never\n"
+
+	var out bytes.Buffer
+	if err := runREPL(context.Background(), strings.NewReader(in), &out, query); err != nil {
+		t.Fatalf("runREPL: %v", err)
+	}
+	if got, want := strings.Join(queries, "|"), "SELECT Name, CityCount FROM Counts|SELEC oops|SELECT again"; got != want {
+		t.Errorf("statements run = %q, want %q", got, want)
+	}
+	table := "Name            CityCount\nGermany         3\nUnited Kingdom  4\n(2 rows)\n"
+	want := replPrompt + table + replPrompt + replPrompt + "error: syntax error\n" + replPrompt + table + replPrompt
+	if got := out.String(); got != want {
+		t.Errorf("REPL output = %q, want %q", got, want)
+	}
+}
+
+func TestRunREPLEOF(t *testing.T) {
+	query := func(context.Context, string) ([]string, [][]string, error) { return nil, nil, nil }
+	var out bytes.Buffer
+	if err := runREPL(context.Background(), strings.NewReader("SELECT 1 WHERE FALSE"), &out, query); err != nil {
+		t.Fatalf("runREPL: %v", err)
+	}
+	if got, want := out.String(), replPrompt+"(0 rows)\n"+replPrompt+"\n"; got != want {
+		t.Errorf("REPL output = %q, want %q", got, want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	for _, tc := range []struct {
+		v    *structpb.Value
+		want string
+	}{
+		{structpb.NewStringValue("49"), "49"},
+		{structpb.NewNumberValue(1.5), "1.5"},
+		{structpb.NewBoolValue(true), "true"},
+		{structpb.NewNullValue(), "NULL"},
+		{structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("Berlin"), structpb.NewNullValue()}}), "[Berlin, NULL]"},
+	} {
+		if got := formatValue(tc.v); got != tc.want {
+			t.Errorf("formatValue(%v) = %q, want %q", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestFormatColumn(t *testing.T) {
+	typ := func(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+	arrayOf := func(code sppb.TypeCode) *sppb.Type {
+		return &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: typ(code)}
+	}
+	list := func(values ...*structpb.Value) *structpb.Value {
+		return structpb.NewListValue(&structpb.ListValue{Values: values})
+	}
+	for _, tc := range []struct {
+		desc string
+		t    *sppb.Type
+		v    *structpb.Value
+		want string
+	}{
+		{"INT64", typ(sppb.TypeCode_INT64), structpb.NewStringValue("9223372036854775807"), "9223372036854775807"},
+		{"STRING", typ(sppb.TypeCode_STRING), structpb.NewStringValue("Berlin"), "Berlin"},
+		{"BOOL", typ(sppb.TypeCode_BOOL), structpb.NewBoolValue(false), "false"},
+		{"FLOAT64", typ(sppb.TypeCode_FLOAT64), structpb.NewNumberValue(0.1), "0.1"},
+		{"FLOAT64 NaN", typ(sppb.TypeCode_FLOAT64), structpb.NewStringValue("NaN"), "NaN"},
+		{"FLOAT64 infinity", typ(sppb.TypeCode_FLOAT64), structpb.NewStringValue("-Infinity"), "-Infinity"},
+		{"TIMESTAMP", typ(sppb.TypeCode_TIMESTAMP), structpb.NewStringValue("2017-03-01T12:00:00.123456Z"), "2017-03-01T12:00:00.123456Z"},
+		{"DATE", typ(sppb.TypeCode_DATE), structpb.NewStringValue("1871-01-18"), "1871-01-18"},
+		{"BYTES", typ(sppb.TypeCode_BYTES), structpb.NewStringValue("aGVsbG8="), "aGVsbG8="},
+		{"NUMERIC", typ(sppb.TypeCode_NUMERIC), structpb.NewStringValue("3.141592653"), "3.141592653"},
+		{"NUMERIC integer", typ(sppb.TypeCode_NUMERIC), structpb.NewStringValue("100"), "100"},
+		{"NUMERIC 38 digits", typ(sppb.TypeCode_NUMERIC), structpb.NewStringValue("99999999999999999999999999999.999999999"), "99999999999999999999999999999.999999999"},
+		{"PostgreSQL numeric", &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}, structpb.NewStringValue("NaN"), "NaN"},
+		{"ARRAY<STRING>", arrayOf(sppb.TypeCode_STRING), list(structpb.NewStringValue("black"), structpb.NewNullValue(), structpb.NewStringValue("gold")), "[black, NULL, gold]"},
+		{"ARRAY<INT64>", arrayOf(sppb.TypeCode_INT64), list(structpb.NewStringValue("1"), structpb.NewStringValue("2")), "[1, 2]"},
+		{"ARRAY<BOOL>", arrayOf(sppb.TypeCode_BOOL), list(structpb.NewBoolValue(true)), "[true]"},
+		{"empty ARRAY", arrayOf(sppb.TypeCode_STRING), list(), "[]"},
+	} {
+		if got := formatColumn(tc.t, tc.v); got != tc.want {
+			t.Errorf("formatColumn(%s) = %q, want %q", tc.desc, got, tc.want)
+		}
+	}
+
+	for _, code := range []sppb.TypeCode{
+		sppb.TypeCode_INT64, sppb.TypeCode_STRING, sppb.TypeCode_BOOL, sppb.TypeCode_FLOAT64,
+		sppb.TypeCode_TIMESTAMP, sppb.TypeCode_DATE, sppb.TypeCode_BYTES, sppb.TypeCode_NUMERIC,
+		sppb.TypeCode_ARRAY,
+	} {
+		if got := formatColumn(typ(code), structpb.NewNullValue()); got != "NULL" {
+			t.Errorf("formatColumn(NULL %v) = %q, want NULL", code, got)
+		}
+	}
+}
+
+func TestPrintRow(t *testing.T) {
+	row, err := spanner.NewRow([]string{"CountryId", "Name", "Colours", "Mayor"}, []interface{}{
+		spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_INT64}, Value: structpb.NewStringValue("49")},
+		spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_STRING}, Value: structpb.NewStringValue("Germany")},
+		spanner.GenericColumnValue{
+			Type:  &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_STRING}},
+			Value: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("black"), structpb.NewStringValue("red")}}),
+		},
+		spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_STRING}, Value: structpb.NewNullValue()},
+	})
+	if err != nil {
+		t.Fatalf("NewRow: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := printRow(&buf, row); err != nil {
+		t.Fatalf("printRow: %v", err)
+	}
+	if got, want := buf.String(), "49\tGermany\t[black, red]\tNULL\n"; got != want {
+		t.Errorf("printRow = %q, want %q", got, want)
+	}
+}
+
+func TestRunSQLFileNumeric(t *testing.T) {
+	defer testDSN(t)()
+	dir, err := ioutil.TempDir("", "sqlfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "query.sql")
+	if err := ioutil.WriteFile(path, []byte("SELECT GDP FROM Countries WHERE CountryId = 49"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A float64 would print this GDP as 4.0721917360001235e+12.
+	const gdp = "4072191736000.123456789"
+	defer func(old func(context.Context, *spanner.Client, ...spanner.ApplyOption) error) { loadPresets = old }(loadPresets)
+	loadPresets = func(ctx context.Context, client *spanner.Client, opts ...spanner.ApplyOption) error {
+		if err := spannerarrays.LoadPresets(ctx, client, opts...); err != nil {
+			return err
+		}
+		r, _ := new(big.Rat).SetString(gdp)
+		return spannerarrays.SetGDP(ctx, client, 49, r)
+	}
+	var buf bytes.Buffer
+	defer func(old io.Writer) { stdout = old }(stdout)
+	stdout = &buf
+	defer func(old string) { *sqlFile = old }(*sqlFile)
+	*sqlFile = path
+
+	if err := run(context.Background()); err != nil {
+		t.Fatalf("run() with --sql-file: %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, gdp) {
+		t.Errorf("output of --sql-file doesn't contain the GDP %s with full precision:\n%s", gdp, got)
+	}
+}
+
+func TestCachedQuery(t *testing.T) {
+	cache, err := spannerarrays.NewQueryCache(10, time.Minute)
+	if err != nil {
+		t.Fatalf("NewQueryCache: %v", err)
+	}
+	calls := 0
+	query := cachedQuery(cache, "projects/p/instances/i/databases/d", func(ctx context.Context, sql string) ([]string, [][]string, error) {
+		calls++
+		return []string{"Name"}, [][]string{{"Germany"}}, nil
+	})
+
+	var in bytes.Buffer
+	in.WriteString("SELECT Name FROM Countries\nSELECT Name FROM Countries\n")
+	var out bytes.Buffer
+	if err := runREPL(context.Background(), &in, &out, query); err != nil {
+		t.Fatalf("runREPL: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("two identical statements ran %d queries, want 1", calls)
+	}
+	if got := strings.Count(out.String(), "Germany"); got != 2 {
+		t.Errorf("output contains Germany %d times, want 2:\n%s", got, out.String())
+	}
+}
+
+func TestReadSQLFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "query.sql")
+	if err := ioutil.WriteFile(path, []byte("\nSELECT Name\nFROM Countries;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sql, err := readSQLFile(path); err != nil || sql != "SELECT Name\nFROM Countries" {
+		t.Errorf("readSQLFile = %q, %v, want the query without the semicolon", sql, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(" ;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSQLFile(path); err == nil {
+		t.Error("readSQLFile(blank file) succeeded, want an error")
+	}
+	if _, err := readSQLFile(filepath.Join(dir, "missing.sql")); err == nil {
+		t.Error("readSQLFile(missing file) succeeded, want an error")
+	}
+}
+
+func TestRunSQLFile(t *testing.T) {
+	defer testDSN(t)()
+	dir, err := ioutil.TempDir("", "sqlfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "query.sql")
+	if err := ioutil.WriteFile(path, []byte("SELECT Name FROM Countries ORDER BY Name"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	defer func(old io.Writer) { stdout = old }(stdout)
+	stdout = &buf
+	defer func(old string) { *sqlFile = old }(*sqlFile)
+	*sqlFile = path
+
+	if err := run(context.Background()); err != nil {
+		t.Fatalf("run() with --sql-file: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{"Name", "Germany", "United Kingdom", "(2 rows)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output of --sql-file doesn't contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/net/context"
+)
+
+// shutdownSignals are the signals which make the sample stop early and clean up.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// shutdownContext returns a context which is cancelled when the process receives one of sigs,
+// and a function which stops listening for them. When a signal arrives, a message is written
+// to w; run then returns from its current step and its deferred cleanup still drops the
+// database, because the cleanup doesn't use the cancelled context.
+func shutdownContext(parent context.Context, w io.Writer, sigs ...os.Signal) (context.Context, func()) {
+	ctx, stop := signal.NotifyContext(parent, sigs...)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			if parent.Err() == nil {
+				fmt.Fprintln(w, "shutting down, cleaning up...")
+			}
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		wg.Wait()
+		stop()
+	}
+}
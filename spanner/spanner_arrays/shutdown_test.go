@@ -0,0 +1,74 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestShutdownContext(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, stop := shutdownContext(context.Background(), &buf, syscall.SIGUSR1)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("sending SIGUSR1: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("context not cancelled after SIGUSR1")
+	}
+	stop()
+	if got := buf.String(); !strings.Contains(got, "shutting down, cleaning up...") {
+		t.Errorf("output after signal = %q, want the shutdown message", got)
+	}
+}
+
+func TestShutdownContextStop(t *testing.T) {
+	var buf bytes.Buffer
+	_, stop := shutdownContext(context.Background(), &buf, syscall.SIGUSR1)
+	stop()
+	if buf.Len() != 0 {
+		t.Errorf("output without signal = %q, want none", buf.String())
+	}
+}
+
+func TestRunCleansUpOnCancel(t *testing.T) {
+	defer testDSN(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the context in the middle of the run, as a signal would.
+	defer func(old func(context.Context, *spanner.Client, ...spanner.ApplyOption) error) { loadPresets = old }(loadPresets)
+	loadPresets = func(ctx context.Context, _ *spanner.Client, _ ...spanner.ApplyOption) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := run(ctx); err == nil {
+		t.Fatal("run() with cancelled context succeeded, want an error")
+	}
+
+	admin, err := database.NewDatabaseAdminClient(context.Background(), clientOptions()...)
+	if err != nil {
+		t.Fatalf("NewDatabaseAdminClient: %v", err)
+	}
+	defer admin.Close()
+	_, err = admin.GetDatabase(context.Background(), &adminpb.GetDatabaseRequest{Name: *dsn})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetDatabase(%q) after cancelled run: got err %v, want NotFound", *dsn, err)
+	}
+}
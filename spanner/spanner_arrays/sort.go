@@ -0,0 +1,57 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+// sortMode is how the countries are sorted before they are printed.
+type sortMode string
+
+const (
+	// sortNone keeps the order of the query.
+	sortNone sortMode = "none"
+
+	// sortName sorts by the bytes of the names, like Spanner's ORDER BY does.
+	sortName sortMode = "name"
+
+	// sortNameCI sorts by the names in Unicode collation order, ignoring case, so that
+	// e.g. "belgium" comes before "Chile" and "Österreich" next to "Oman".
+	sortNameCI sortMode = "name-ci"
+)
+
+// sortModes lists the values accepted by the --sort flag.
+var sortModes = []string{string(sortNone), string(sortName), string(sortNameCI)}
+
+// parseSortMode returns the sort mode called s.
+func parseSortMode(s string) (sortMode, error) {
+	switch m := sortMode(s); m {
+	case sortNone, sortName, sortNameCI:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid --sort %q, want one of: %s", s, strings.Join(sortModes, ", "))
+}
+
+// sortCountries sorts countries in place by mode. The sort is stable, so countries with
+// equal names stay in the order of the query.
+func sortCountries(countries []spannerarrays.Country, mode sortMode) {
+	switch mode {
+	case sortName:
+		sort.SliceStable(countries, func(i, j int) bool { return countries[i].Name < countries[j].Name })
+	case sortNameCI:
+		c := collate.New(language.Und, collate.IgnoreCase)
+		sort.SliceStable(countries, func(i, j int) bool {
+			return c.CompareString(countries[i].Name, countries[j].Name) < 0
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/golang-samples/spanner/spanner_arrays/spannerarrays"
+)
+
+func TestSortCountries(t *testing.T) {
+	names := func(countries []spannerarrays.Country) string {
+		var out []string
+		for _, c := range countries {
+			out = append(out, c.Name)
+		}
+		return strings.Join(out, ", ")
+	}
+	for _, tc := range []struct {
+		mode sortMode
+		want string
+	}{
+		{sortNone, "germany, Belgium, chile, Austria"},
+		{sortName, "Austria, Belgium, chile, germany"},
+		{sortNameCI, "Austria, Belgium, chile, germany"},
+	} {
+		countries := []spannerarrays.Country{{Name: "germany"}, {Name: "Belgium"}, {Name: "chile"}, {Name: "Austria"}}
+		sortCountries(countries, tc.mode)
+		if got := names(countries); got != tc.want {
+			t.Errorf("sortCountries(%s) = %s, want %s", tc.mode, got, tc.want)
+		}
+	}
+
+	countries := []spannerarrays.Country{{Name: "denmark"}, {Name: "Chile"}, {Name: "austria"}, {Name: "Belgium"}}
+	sortCountries(countries, sortName)
+	if got, want := names(countries), "Belgium, Chile, austria, denmark"; got != want {
+		t.Errorf("sortCountries(name) = %s, want byte order %s", got, want)
+	}
+	sortCountries(countries, sortNameCI)
+	if got, want := names(countries), "austria, Belgium, Chile, denmark"; got != want {
+		t.Errorf("sortCountries(name-ci) = %s, want %s", got, want)
+	}
+}
+
+func TestParseSortMode(t *testing.T) {
+	for _, s := range sortModes {
+		if m, err := parseSortMode(s); err != nil || string(m) != s {
+			t.Errorf("parseSortMode(%q) = %q, %v, want %q", s, m, err, s)
+		}
+	}
+	if _, err := parseSortMode("population"); err == nil {
+		t.Error("parseSortMode(population) succeeded, want an error")
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"math/big"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// CountCitiesPerCountry returns the number of cities of every country, keyed by country name.
+// Because of the LEFT JOIN, countries without any cities are included with a count of 0.
+// Soft-deleted cities aren't counted.
+func CountCitiesPerCountry(ctx context.Context, client *spanner.Client) (map[string]int64, error) {
+	it := client.Single().Query(ctx, spanner.NewStatement(`
+		SELECT a.Name, COUNT(b.CityId) FROM Countries a
+		LEFT JOIN Cities b ON a.CountryId = b.CountryId`+notDeletedFilter+`
+		GROUP BY a.Name`))
+	defer it.Stop()
+
+	counts := map[string]int64{}
+	for {
+		row, err := it.Next()
+		if err == iterator.Done {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read results: %w", err)
+		}
+
+		var (
+			name  string
+			count int64
+		)
+		if err := row.Columns(&name, &count); err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		counts[name] = count
+	}
+}
+
+// TotalPopulationByCountry returns the sum of the populations of the cities of every
+// country, keyed by country name. Countries without cities, and cities whose population is
+// NULL, count as 0. Soft-deleted cities are left out.
+func TotalPopulationByCountry(ctx context.Context, client *spanner.Client) (map[string]int64, error) {
+	// SUM ignores NULL inputs but returns NULL when it has no non-NULL input at all, which is
+	// the case for a country whose only row from the LEFT JOIN has no city.
+	it := client.Single().Query(ctx, spanner.NewStatement(`
+		SELECT a.Name, IFNULL(SUM(b.Population), 0) FROM Countries a
+		LEFT JOIN Cities b ON a.CountryId = b.CountryId`+notDeletedFilter+`
+		GROUP BY a.Name`))
+	defer it.Stop()
+
+	totals := map[string]int64{}
+	for {
+		row, err := it.Next()
+		if err == iterator.Done {
+			return totals, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read results: %w", err)
+		}
+
+		var (
+			name  string
+			total int64
+		)
+		if err := row.Columns(&name, &total); err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		totals[name] = total
+	}
+}
+
+// AverageCityPopulation returns the mean population of all cities which aren't soft-deleted,
+// or 0 if there are none.
+func AverageCityPopulation(ctx context.Context, client *spanner.Client) (float64, error) {
+	// AVG of an INT64 column is a FLOAT64, which is NULL when there are no rows at all.
+	it := client.Single().Query(ctx, spanner.NewStatement("SELECT AVG(b.Population) FROM Cities b WHERE "+notDeleted))
+	defer it.Stop()
+	row, err := it.Next()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read results: %w", err)
+	}
+	var avg spanner.NullFloat64
+	if err := row.Column(0, &avg); err != nil {
+		return 0, fmt.Errorf("failed to read row: %w", err)
+	}
+	return avg.Float64, nil
+}
+
+// SetGDP sets the GDP of the country countryID to gdp, or to NULL if gdp is nil.
+// NUMERIC holds up to 38 digits before and 9 after the decimal point exactly, unlike
+// FLOAT64, so amounts such as 0.1 dollars are stored without rounding.
+func SetGDP(ctx context.Context, client *spanner.Client, countryID int64, gdp *big.Rat) error {
+	defer invalidateCache(client)
+	v := spanner.NullNumeric{}
+	if gdp != nil {
+		v = spanner.NullNumeric{Numeric: *gdp, Valid: true}
+	}
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.UpdateMap("Countries", map[string]interface{}{"CountryId": countryID, "GDP": v}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set the GDP of country %d: %v", countryID, err)
+	}
+	return nil
+}
+
+// TotalGDP returns the sum of the GDPs of all countries, computed exactly. Countries whose
+// GDP is NULL are ignored, and the total is 0 if no country has one.
+func TotalGDP(ctx context.Context, client *spanner.Client) (*big.Rat, error) {
+	it := client.Single().Query(ctx, spanner.NewStatement("SELECT SUM(GDP) FROM Countries"))
+	defer it.Stop()
+	row, err := it.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+	var total spanner.NullNumeric
+	if err := row.Column(0, &total); err != nil {
+		return nil, fmt.Errorf("failed to read row: %w", err)
+	}
+	if !total.Valid {
+		return new(big.Rat), nil
+	}
+	return &total.Numeric, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+func TestCountCitiesPerCountry(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := InsertCountry(ctx, client, 33, "France"); err != nil {
+		t.Fatalf("InsertCountry: %v", err)
+	}
+
+	counts, err := CountCitiesPerCountry(ctx, client)
+	if err != nil {
+		t.Fatalf("CountCitiesPerCountry: %v", err)
+	}
+	want := map[string]int64{"Germany": 3, "United Kingdom": 4, "France": 0}
+	if len(counts) != len(want) {
+		t.Errorf("CountCitiesPerCountry = %v, want %v", counts, want)
+	}
+	for name, n := range want {
+		if got, ok := counts[name]; !ok || got != n {
+			t.Errorf("cities in %s = %d (present: %v), want %d", name, got, ok, n)
+		}
+	}
+}
+
+func TestTotalPopulationByCountry(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := InsertCountry(ctx, client, 33, "France"); err != nil {
+		t.Fatalf("InsertCountry: %v", err)
+	}
+
+	totals, err := TotalPopulationByCountry(ctx, client)
+	if err != nil {
+		t.Fatalf("TotalPopulationByCountry: %v", err)
+	}
+	want := map[string]int64{
+		"Germany":        3605000 + 1739117 + 486854,
+		"United Kingdom": 8788000 + 465700 + 428100 + 304636,
+		"France":         0,
+	}
+	if len(totals) != len(want) {
+		t.Errorf("TotalPopulationByCountry = %v, want %v", totals, want)
+	}
+	for name, total := range want {
+		if got, ok := totals[name]; !ok || got != total {
+			t.Errorf("population of %s = %d (present: %v), want %d", name, got, ok, total)
+		}
+	}
+}
+
+func TestAverageCityPopulation(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	avg, err := AverageCityPopulation(ctx, client)
+	if err != nil {
+		t.Fatalf("AverageCityPopulation: %v", err)
+	}
+	const presetTotal = 3605000 + 1739117 + 486854 + 8788000 + 465700 + 428100 + 304636
+	if want := float64(presetTotal) / 7; math.Abs(avg-want) > 1e-6 {
+		t.Errorf("AverageCityPopulation = %v, want %v", avg, want)
+	}
+
+	if _, err := client.Apply(ctx, []*spanner.Mutation{spanner.Delete("Cities", spanner.AllKeys())}); err != nil {
+		t.Fatalf("deleting all cities: %v", err)
+	}
+	if avg, err := AverageCityPopulation(ctx, client); err != nil || avg != 0 {
+		t.Errorf("AverageCityPopulation without cities = %v, %v, want 0", avg, err)
+	}
+}
+
+func TestTotalGDP(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	total, err := TotalGDP(ctx, client)
+	if err != nil {
+		t.Fatalf("TotalGDP: %v", err)
+	}
+	if total.Sign() != 0 {
+		t.Errorf("TotalGDP without any GDP = %s, want 0", total.FloatString(9))
+	}
+
+	// 0.1 and 0.2 have no exact FLOAT64 representation, but add up to exactly 0.3 as NUMERIC.
+	if err := SetGDP(ctx, client, 49, big.NewRat(1, 10)); err != nil {
+		t.Fatalf("SetGDP(Germany): %v", err)
+	}
+	if err := SetGDP(ctx, client, 44, big.NewRat(2, 10)); err != nil {
+		t.Fatalf("SetGDP(United Kingdom): %v", err)
+	}
+	if total, err = TotalGDP(ctx, client); err != nil {
+		t.Fatalf("TotalGDP: %v", err)
+	}
+	if want := big.NewRat(3, 10); total.Cmp(want) != 0 {
+		t.Errorf("TotalGDP = %s, want %s", total.FloatString(9), want.FloatString(9))
+	}
+}
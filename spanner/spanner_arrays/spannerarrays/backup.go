@@ -0,0 +1,82 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// MinBackupRetention and MaxBackupRetention limit how far in the future the expiration
+	// time of a new backup may lie.
+	MinBackupRetention = 6 * time.Hour
+	MaxBackupRetention = 366 * 24 * time.Hour
+)
+
+// CreateBackup creates the backup backupID of db in the instance of db and waits until it is
+// complete. Spanner deletes the backup at expire, which must lie between MinBackupRetention
+// and MaxBackupRetention from now.
+func CreateBackup(ctx context.Context, adminClient *database.DatabaseAdminClient, db, backupID string, expire time.Time) error {
+	if err := validateBackupExpiry(expire, time.Now()); err != nil {
+		return err
+	}
+	instance, _, err := splitDatabase(db)
+	if err != nil {
+		return err
+	}
+	op, err := adminClient.CreateBackup(ctx, &adminpb.CreateBackupRequest{
+		Parent:   instance,
+		BackupId: backupID,
+		Backup: &adminpb.Backup{
+			Database:   db,
+			ExpireTime: timestamppb.New(expire),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "creating backup", "database", db, "backup", backupID, "operation", op.Name())
+	backup, err := op.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("backup %s failed: %v", backupID, err)
+	}
+	slog.InfoContext(ctx, "backup created", "backup", backup.Name, "sizeBytes", backup.SizeBytes, "expires", backup.ExpireTime.AsTime())
+	return nil
+}
+
+// validateBackupExpiry checks that a backup created at now may expire at expire.
+func validateBackupExpiry(expire, now time.Time) error {
+	switch d := expire.Sub(now); {
+	case d < MinBackupRetention:
+		return fmt.Errorf("backup expiration %s is less than %v in the future", expire.Format(time.RFC3339), MinBackupRetention)
+	case d > MaxBackupRetention:
+		return fmt.Errorf("backup expiration %s is more than %v in the future", expire.Format(time.RFC3339), MaxBackupRetention)
+	}
+	return nil
+}
+
+// ListBackups returns the backups of the instance projects/P/instances/I.
+func ListBackups(ctx context.Context, adminClient *database.DatabaseAdminClient, instance string) ([]*adminpb.Backup, error) {
+	var backups []*adminpb.Backup
+	it := adminClient.ListBackups(ctx, &adminpb.ListBackupsRequest{Parent: instance})
+	for {
+		backup, err := it.Next()
+		if err == iterator.Done {
+			return backups, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list backups of %s: %v", instance, err)
+		}
+		backups = append(backups, backup)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+// fakeBackups is a database admin server which keeps the backups it creates in memory.
+type fakeBackups struct {
+	adminpb.UnimplementedDatabaseAdminServer
+	t *testing.T
+
+	mu      sync.Mutex
+	backups []*adminpb.Backup
+}
+
+func (f *fakeBackups) CreateBackup(ctx context.Context, req *adminpb.CreateBackupRequest) (*longrunningpb.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b := &adminpb.Backup{
+		Name:       req.Parent + "/backups/" + req.BackupId,
+		Database:   req.Backup.Database,
+		ExpireTime: req.Backup.ExpireTime,
+		State:      adminpb.Backup_READY,
+	}
+	f.backups = append(f.backups, b)
+	return doneOperation(f.t, req.Parent+"/backups/"+req.BackupId+"/operations/1", b), nil
+}
+
+func (f *fakeBackups) ListBackups(ctx context.Context, req *adminpb.ListBackupsRequest) (*adminpb.ListBackupsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &adminpb.ListBackupsResponse{Backups: f.backups}, nil
+}
+
+func TestCreateAndListBackups(t *testing.T) {
+	const (
+		instance = "projects/p/instances/i"
+		db       = instance + "/databases/d"
+	)
+	admin, cleanup := newFakeAdminClient(t, &fakeBackups{t: t})
+	defer cleanup()
+	ctx := context.Background()
+
+	expire := time.Now().Add(24 * time.Hour)
+	if err := CreateBackup(ctx, admin, db, "b1", expire); err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	backups, err := ListBackups(ctx, admin, instance)
+	if err != nil {
+		t.Fatalf("ListBackups: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("ListBackups returned %d backups, want 1", len(backups))
+	}
+	if got, want := backups[0].Name, instance+"/backups/b1"; got != want {
+		t.Errorf("backup name = %q, want %q", got, want)
+	}
+	if got := backups[0].Database; got != db {
+		t.Errorf("backup of database %q, want %q", got, db)
+	}
+	if got := backups[0].ExpireTime.AsTime(); !got.Equal(expire) {
+		t.Errorf("backup expires at %v, want %v", got, expire)
+	}
+}
+
+func TestValidateBackupExpiry(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		expire time.Time
+		ok     bool
+	}{
+		{now.Add(-time.Hour), false},
+		{now.Add(time.Hour), false},
+		{now.Add(MinBackupRetention + time.Minute), true},
+		{now.Add(30 * 24 * time.Hour), true},
+		{now.Add(MaxBackupRetention + time.Hour), false},
+	} {
+		if err := validateBackupExpiry(tc.expire, now); (err == nil) != tc.ok {
+			t.Errorf("validateBackupExpiry(now%+v) = %v, want ok = %v", tc.expire.Sub(now), err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultBatchSize is the number of mutations LoadPresets and LoadFromFile apply per commit.
+// Spanner limits the number of mutations in a single commit to 40,000, where every
+// column of every inserted row counts as one mutation, so even at maxCellsPerMutation
+// cells per row a batch stays far below the limit.
+const DefaultBatchSize = 1000
+
+// maxMutationsPerCommit is Spanner's limit on the mutations of a single commit.
+const maxMutationsPerCommit = 40000
+
+// maxCellsPerMutation is the most cells a single mutation of this package can count
+// against maxMutationsPerCommit: all seven columns of Cities, the widest table, plus the
+// entry of the CitiesByName index.
+const maxCellsPerMutation = 8
+
+// LoadConcurrency is the number of batches LoadPresets, UpsertPresets and LoadFromFile
+// commit at the same time. The default of 1 commits them one after the other.
+var LoadConcurrency = 1
+
+// ApplyBatched applies mutations in consecutive commits of at most batchSize mutations each.
+// The batches are committed in order, so parent rows placed before their children are
+// written first. The load is not atomic: if a batch fails, the earlier batches stay committed.
+// opts are passed to every commit.
+func ApplyBatched(ctx context.Context, client *spanner.Client, mutations []*spanner.Mutation, batchSize int, opts ...spanner.ApplyOption) error {
+	defer invalidateCache(client)
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d, must be positive", batchSize)
+	}
+	bs := batches(mutations, batchSize)
+	if err := validateBatches(bs); err != nil {
+		return err
+	}
+	for i, batch := range bs {
+		commitTS, err := client.Apply(ctx, batch, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to apply batch %d (mutations %d to %d): %v", i, i*batchSize, i*batchSize+len(batch)-1, err)
+		}
+		MutationsApplied.Add(int64(len(batch)))
+		slog.DebugContext(ctx, "batch committed", "batch", i, "mutations", len(batch), "commit_timestamp", commitTS)
+	}
+	return nil
+}
+
+// ApplyBatchedConcurrently applies the groups of mutations in order, splitting each group
+// into batches of at most batchSize mutations like ApplyBatched and committing up to
+// concurrency batches of a group at the same time. A group is only started once all the
+// batches of the previous one are committed, so parent rows must be placed in an earlier
+// group than their interleaved children. Within a group the batches may commit in any order.
+//
+// If a batch fails, no further batches are started and the error of the first failure is
+// returned; batches already committed, including ones running concurrently with the
+// failed one, stay committed.
+func ApplyBatchedConcurrently(ctx context.Context, client *spanner.Client, groups [][]*spanner.Mutation, batchSize, concurrency int, opts ...spanner.ApplyOption) error {
+	defer invalidateCache(client)
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d, must be positive", batchSize)
+	}
+	if concurrency <= 0 {
+		return fmt.Errorf("invalid concurrency %d, must be positive", concurrency)
+	}
+	grouped := make([][][]*spanner.Mutation, len(groups))
+	for gi, group := range groups {
+		grouped[gi] = batches(group, batchSize)
+		if err := validateBatches(grouped[gi]); err != nil {
+			return fmt.Errorf("group %d: %v", gi, err)
+		}
+	}
+	for gi, bs := range grouped {
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(concurrency)
+		for i, batch := range bs {
+			i, batch := i, batch
+			g.Go(func() error {
+				if err := gctx.Err(); err != nil {
+					return err
+				}
+				commitTS, err := client.Apply(gctx, batch, opts...)
+				if err != nil {
+					return fmt.Errorf("failed to apply batch %d of group %d (mutations %d to %d): %v", i, gi, i*batchSize, i*batchSize+len(batch)-1, err)
+				}
+				MutationsApplied.Add(int64(len(batch)))
+				slog.DebugContext(gctx, "batch committed", "group", gi, "batch", i, "mutations", len(batch), "commit_timestamp", commitTS)
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadTransactional applies mutations in a single read-write transaction, buffering all of
+// them before the commit, so that either every mutation is written or, if any of them fails,
+// none is. Unlike ApplyBatched there are no earlier batches to roll back by hand.
+//
+// The mutations still form one commit, so they are subject to Spanner's limit of 40,000
+// mutations per commit, counting every column of every row. Larger loads are rejected
+// before anything is sent; they have to be split with ApplyBatched and give up atomicity.
+func LoadTransactional(ctx context.Context, client *spanner.Client, mutations []*spanner.Mutation) error {
+	defer invalidateCache(client)
+	if err := validateMutationCount(mutations); err != nil {
+		return fmt.Errorf("%v, split them with ApplyBatched", err)
+	}
+	commitTS, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		return txn.BufferWrite(mutations)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply %d mutations in one transaction, none were written: %v", len(mutations), err)
+	}
+	MutationsApplied.Add(int64(len(mutations)))
+	slog.DebugContext(ctx, "transaction committed", "mutations", len(mutations), "commit_timestamp", commitTS)
+	return nil
+}
+
+// BatchWriteCountries commits the groups of mutations, typically a country and its cities
+// each, with the BatchWrite API. Every group is applied atomically, but independently of
+// the others: Spanner may commit them in any order, several in one commit, and a failing
+// group doesn't prevent the others from being written. If any group fails, the error lists
+// the failed groups by index together with their status; all the others are committed.
+func BatchWriteCountries(ctx context.Context, client *spanner.Client, groups [][]*spanner.Mutation) error {
+	defer invalidateCache(client)
+	mgs := make([]*spanner.MutationGroup, len(groups))
+	for i, g := range groups {
+		mgs[i] = &spanner.MutationGroup{Mutations: g}
+	}
+	var (
+		failures []string
+		failed   int
+	)
+	err := client.BatchWrite(ctx, mgs).Do(func(r *sppb.BatchWriteResponse) error {
+		// Each response reports the outcome of the groups listed in Indexes.
+		if code := codes.Code(r.GetStatus().GetCode()); code != codes.OK {
+			failures = append(failures, fmt.Sprintf("groups %v: %v: %s", r.GetIndexes(), code, r.GetStatus().GetMessage()))
+			failed += len(r.GetIndexes())
+			return nil
+		}
+		for _, i := range r.GetIndexes() {
+			MutationsApplied.Add(int64(len(groups[i])))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to batch write %d mutation groups: %v", len(groups), err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d mutation groups failed: %s", failed, len(groups), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// CommitDelayOptions returns the apply options which let Spanner delay each commit by up to d,
+// so that it can batch concurrent writes together for a higher throughput at the cost of a
+// higher commit latency. A zero d returns no options, leaving the delay to Spanner.
+func CommitDelayOptions(d time.Duration) []spanner.ApplyOption {
+	if d == 0 {
+		return nil
+	}
+	return []spanner.ApplyOption{spanner.ApplyCommitOptions(spanner.CommitOptions{MaxCommitDelay: &d})}
+}
+
+// validateMutationCount returns an error if committing mutations together would exceed
+// Spanner's limit of mutations per commit, so that an oversized commit is rejected before
+// anything is sent. Spanner counts one mutation for every column written in every row,
+// including the secondary index entries it updates, and one for every row or range
+// deleted. spanner.Mutation doesn't expose its columns, so every mutation is counted as
+// maxCellsPerMutation, which is an upper bound.
+func validateMutationCount(mutations []*spanner.Mutation) error {
+	if n := len(mutations) * maxCellsPerMutation; n > maxMutationsPerCommit {
+		return fmt.Errorf("%d mutations may write up to %d cells, more than the %d Spanner allows in one commit", len(mutations), n, maxMutationsPerCommit)
+	}
+	return nil
+}
+
+// validateBatches checks every batch with validateMutationCount.
+func validateBatches(bs [][]*spanner.Mutation) error {
+	for i, batch := range bs {
+		if err := validateMutationCount(batch); err != nil {
+			return fmt.Errorf("batch %d: %v, use a smaller batch size", i, err)
+		}
+	}
+	return nil
+}
+
+// batches splits mutations into consecutive slices of at most size mutations.
+func batches(mutations []*spanner.Mutation, size int) [][]*spanner.Mutation {
+	var out [][]*spanner.Mutation
+	for len(mutations) > size {
+		out = append(out, mutations[:size])
+		mutations = mutations[size:]
+	}
+	if len(mutations) > 0 {
+		out = append(out, mutations)
+	}
+	return out
+}
@@ -0,0 +1,254 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+func TestBatches(t *testing.T) {
+	mx := make([]*spanner.Mutation, 7)
+	for _, tc := range []struct {
+		size int
+		want []int
+	}{
+		{size: 3, want: []int{3, 3, 1}},
+		{size: 7, want: []int{7}},
+		{size: 10, want: []int{7}},
+	} {
+		got := batches(mx, tc.size)
+		var sizes []int
+		for _, b := range got {
+			sizes = append(sizes, len(b))
+		}
+		if fmt.Sprint(sizes) != fmt.Sprint(tc.want) {
+			t.Errorf("batches(7 mutations, %d) sizes = %v, want %v", tc.size, sizes, tc.want)
+		}
+	}
+	if got := batches(nil, 3); len(got) != 0 {
+		t.Errorf("batches(nil, 3) = %d batches, want 0", len(got))
+	}
+}
+
+func TestApplyBatched(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	const cities = 5000
+	country := CountryData{ID: 1, Name: "Bigland"}
+	for i := 0; i < cities; i++ {
+		country.Cities = append(country.Cities, CityData{ID: int64(i + 1), Name: fmt.Sprintf("City %d", i+1)})
+	}
+	mx, err := dataMutations([]CountryData{country})
+	if err != nil {
+		t.Fatalf("dataMutations: %v", err)
+	}
+	if err := ApplyBatched(ctx, client, mx, 700); err != nil {
+		t.Fatalf("ApplyBatched: %v", err)
+	}
+
+	it := client.Single().Query(ctx, spanner.NewStatement("SELECT COUNT(*) FROM Cities WHERE CountryId = 1"))
+	defer it.Stop()
+	row, err := it.Next()
+	if err != nil {
+		t.Fatalf("counting cities: %v", err)
+	}
+	var n int64
+	if err := row.Column(0, &n); err != nil {
+		t.Fatalf("Column(0): %v", err)
+	}
+	if n != cities {
+		t.Errorf("found %d cities after ApplyBatched, want %d", n, cities)
+	}
+}
+
+func TestApplyBatchedConcurrently(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	const countries, cities = 20, 250
+	var data []CountryData
+	for c := 0; c < countries; c++ {
+		country := CountryData{ID: int64(c + 1), Name: fmt.Sprintf("Country %d", c+1)}
+		for i := 0; i < cities; i++ {
+			country.Cities = append(country.Cities, CityData{ID: int64(i + 1), Name: fmt.Sprintf("City %d", i+1)})
+		}
+		data = append(data, country)
+	}
+	mx, err := dataMutations(data)
+	if err != nil {
+		t.Fatalf("dataMutations: %v", err)
+	}
+	// With a batch size of 100 every batch of cities belongs to a single country, but the
+	// countries are only committed in the first group, so the cities never arrive first.
+	groups := [][]*spanner.Mutation{mx[:countries], mx[countries:]}
+	if err := ApplyBatchedConcurrently(ctx, client, groups, 100, 8); err != nil {
+		t.Fatalf("ApplyBatchedConcurrently: %v", err)
+	}
+	for c := int64(1); c <= countries; c++ {
+		if n := countCities(t, client, c); n != cities {
+			t.Errorf("found %d cities of country %d, want %d", n, c, cities)
+		}
+	}
+}
+
+func TestApplyBatchedConcurrentlyInvalid(t *testing.T) {
+	ctx := context.Background()
+	if err := ApplyBatchedConcurrently(ctx, nil, nil, 0, 1); err == nil {
+		t.Error("ApplyBatchedConcurrently with batch size 0 succeeded, want an error")
+	}
+	if err := ApplyBatchedConcurrently(ctx, nil, nil, 1, 0); err == nil {
+		t.Error("ApplyBatchedConcurrently with concurrency 0 succeeded, want an error")
+	}
+}
+
+func TestLoadPresetsConcurrently(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	defer func(old int) { LoadConcurrency = old }(LoadConcurrency)
+	LoadConcurrency = 4
+	// newTestClient has already loaded the presets, so overwrite them concurrently.
+	if err := UpsertPresets(context.Background(), client); err != nil {
+		t.Fatalf("UpsertPresets with a concurrency of 4: %v", err)
+	}
+	if n := countCities(t, client, 49); n != 3 {
+		t.Errorf("found %d cities of Germany, want 3", n)
+	}
+}
+
+// countCities returns the number of cities of the country countryID.
+func countCities(t *testing.T, client *spanner.Client, countryID int64) int64 {
+	ctx := context.Background()
+	stmt := spanner.Statement{SQL: "SELECT COUNT(*) FROM Cities WHERE CountryId = @id", Params: map[string]interface{}{"id": countryID}}
+	it := client.Single().Query(ctx, stmt)
+	defer it.Stop()
+	row, err := it.Next()
+	if err != nil {
+		t.Fatalf("counting cities: %v", err)
+	}
+	var n int64
+	if err := row.Column(0, &n); err != nil {
+		t.Fatalf("Column(0): %v", err)
+	}
+	return n
+}
+
+func TestLoadTransactionalRollsBack(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	country := CountryData{ID: 1, Name: "Bigland"}
+	for i := 0; i < 10; i++ {
+		country.Cities = append(country.Cities, CityData{ID: int64(i + 1), Name: fmt.Sprintf("City %d", i+1)})
+	}
+	mx, err := dataMutations([]CountryData{country})
+	if err != nil {
+		t.Fatalf("dataMutations: %v", err)
+	}
+	// The last mutation inserts a city into a country which doesn't exist, so it fails.
+	orphan := spanner.Insert("Cities", []string{"CountryId", "CityId", "Name", "Population"}, []interface{}{int64(2), int64(1), "Nowhere", int64(0)})
+	mx = append(mx, orphan)
+
+	if err := LoadTransactional(ctx, client, mx); err == nil {
+		t.Fatal("LoadTransactional with an orphaned city succeeded, want an error")
+	}
+	if n := countCities(t, client, 1); n != 0 {
+		t.Errorf("found %d cities after the failed LoadTransactional, want none", n)
+	}
+
+	// ApplyBatched commits the batches before the failing one: the country and cities 1 to 7
+	// are in the first two batches of four mutations, the orphan in the third.
+	if err := ApplyBatched(ctx, client, mx, 4); err == nil {
+		t.Fatal("ApplyBatched with an orphaned city succeeded, want an error")
+	}
+	if n := countCities(t, client, 1); n != 7 {
+		t.Errorf("found %d cities after the failed ApplyBatched, want the 7 of the earlier batches", n)
+	}
+}
+
+func TestValidateMutationCount(t *testing.T) {
+	// Every mutation is counted as maxCellsPerMutation cells, so 5,000 of them are exactly
+	// at the limit.
+	row := func(i int) *spanner.Mutation {
+		return spanner.InsertMap("Cities", map[string]interface{}{
+			"CountryId": int64(1), "CityId": int64(i), "Name": "City", "Population": int64(0), "LastModified": spanner.CommitTimestamp,
+		})
+	}
+	var mx []*spanner.Mutation
+	for i := 0; i < 5000; i++ {
+		mx = append(mx, row(i+1))
+	}
+	if err := validateMutationCount(mx); err != nil {
+		t.Errorf("validateMutationCount(5000 rows): %v", err)
+	}
+	mx = append(mx, row(5001))
+	err := validateMutationCount(mx)
+	if err == nil || !strings.Contains(err.Error(), "40008 cells") {
+		t.Errorf("validateMutationCount(5001 rows) = %v, want an error counting 40008 cells", err)
+	}
+
+	// The oversized commit is rejected before the client is used.
+	if err := LoadTransactional(context.Background(), nil, mx); err == nil || !strings.Contains(err.Error(), "ApplyBatched") {
+		t.Errorf("LoadTransactional(5001 rows) = %v, want an error suggesting ApplyBatched", err)
+	}
+	if err := ApplyBatched(context.Background(), nil, mx, len(mx)); err == nil || !strings.Contains(err.Error(), "smaller batch size") {
+		t.Errorf("ApplyBatched(5001 rows in one batch) = %v, want an error suggesting a smaller batch size", err)
+	}
+}
+
+func TestBatchWriteCountries(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	valid, err := dataMutations([]CountryData{{ID: 1, Name: "Groupland", Cities: []CityData{{ID: 1, Name: "Grouptown"}}}})
+	if err != nil {
+		t.Fatalf("dataMutations: %v", err)
+	}
+	// The second group inserts a city into a country which doesn't exist, so it fails.
+	invalid := []*spanner.Mutation{
+		spanner.Insert("Cities", []string{"CountryId", "CityId", "Name", "Population"}, []interface{}{int64(2), int64(1), "Nowhere", int64(0)}),
+	}
+
+	err = BatchWriteCountries(ctx, client, [][]*spanner.Mutation{valid, invalid})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 mutation groups failed") || !strings.Contains(err.Error(), "groups [1]") {
+		t.Errorf("BatchWriteCountries with an invalid group = %v, want an error reporting group 1", err)
+	}
+	if n := countCities(t, client, 1); n != 1 {
+		t.Errorf("found %d cities of the valid group, want 1", n)
+	}
+}
+
+func TestCommitDelayOptions(t *testing.T) {
+	if opts := CommitDelayOptions(0); len(opts) != 0 {
+		t.Errorf("CommitDelayOptions(0) = %d options, want none", len(opts))
+	}
+	if opts := CommitDelayOptions(10 * time.Millisecond); len(opts) != 1 {
+		t.Errorf("CommitDelayOptions(10ms) = %d options, want 1", len(opts))
+	}
+}
+
+func TestApplyBatchedCommitDelay(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	mx, err := dataMutations([]CountryData{{ID: 1, Name: "Delayland", Cities: []CityData{{ID: 1, Name: "Lagtown"}}}})
+	if err != nil {
+		t.Fatalf("dataMutations: %v", err)
+	}
+	if err := ApplyBatched(context.Background(), client, mx, DefaultBatchSize, CommitDelayOptions(10*time.Millisecond)...); err != nil {
+		t.Fatalf("ApplyBatched with a max commit delay of 10ms: %v", err)
+	}
+}
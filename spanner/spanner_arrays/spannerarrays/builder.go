@@ -0,0 +1,81 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+)
+
+// mutationBuilder accumulates the mutations writing rows of the Countries and Cities tables.
+// It checks the required columns of every row as it is added, so that data with a missing
+// ID or name is rejected by Build with all its problems listed, before anything is sent to
+// Spanner, instead of failing the commit or writing a row of zero values.
+type mutationBuilder struct {
+	write func(table string, in map[string]interface{}) *spanner.Mutation
+	mx    []*spanner.Mutation
+	errs  []string
+}
+
+// newMutationBuilder returns a builder which creates its mutations with write, such as
+// spanner.InsertMap or spanner.InsertOrUpdateMap.
+func newMutationBuilder(write func(table string, in map[string]interface{}) *spanner.Mutation) *mutationBuilder {
+	return &mutationBuilder{write: write}
+}
+
+// Country adds the country id called name, with the given flag colours and founding date,
+// which may be NULL.
+func (b *mutationBuilder) Country(id int64, name string, colours []string, founded spanner.NullDate) {
+	if id == 0 {
+		b.errs = append(b.errs, fmt.Sprintf("country %q has no ID", name))
+	}
+	if name == "" {
+		b.errs = append(b.errs, fmt.Sprintf("country %d has no name", id))
+	}
+	if colours == nil {
+		// Colours is NOT NULL, so a country without colours gets an empty array.
+		colours = []string{}
+	}
+	b.mx = append(b.mx, b.write("Countries", map[string]interface{}{
+		"CountryId": id,
+		"Name":      name,
+		"Colours":   colours,
+		"Founded":   founded,
+	}))
+}
+
+// City adds the city cityID of the country countryID, called name.
+func (b *mutationBuilder) City(countryID, cityID int64, name string, population int64) {
+	if countryID == 0 {
+		b.errs = append(b.errs, fmt.Sprintf("city %q has no country ID", name))
+	}
+	if cityID == 0 {
+		b.errs = append(b.errs, fmt.Sprintf("city %q in country %d has no ID", name, countryID))
+	}
+	if name == "" {
+		b.errs = append(b.errs, fmt.Sprintf("city %d in country %d has no name", cityID, countryID))
+	}
+	if population < 0 {
+		b.errs = append(b.errs, fmt.Sprintf("city %d in country %d has a negative population", cityID, countryID))
+	}
+	b.mx = append(b.mx, b.write("Cities", map[string]interface{}{
+		"CountryId":    countryID,
+		"CityId":       cityID,
+		"Name":         name,
+		"Population":   population,
+		"LastModified": spanner.CommitTimestamp,
+	}))
+}
+
+// Build returns the mutations in the order their rows were added, or an error listing every
+// invalid row.
+func (b *mutationBuilder) Build() ([]*spanner.Mutation, error) {
+	if len(b.errs) > 0 {
+		return nil, fmt.Errorf("invalid data: %s", strings.Join(b.errs, ", "))
+	}
+	return b.mx, nil
+}
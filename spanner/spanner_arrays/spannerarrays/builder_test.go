@@ -0,0 +1,94 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+// recordWrites returns a write function for newMutationBuilder which records the tables and
+// rows written, together with a pointer to the records.
+func recordWrites() (func(string, map[string]interface{}) *spanner.Mutation, *[]string) {
+	var writes []string
+	return func(table string, in map[string]interface{}) *spanner.Mutation {
+		writes = append(writes, table+" "+in["Name"].(string))
+		return spanner.InsertMap(table, in)
+	}, &writes
+}
+
+func TestMutationBuilder(t *testing.T) {
+	write, writes := recordWrites()
+	b := newMutationBuilder(write)
+	b.Country(49, "Germany", []string{"black", "red", "gold"}, spanner.NullDate{})
+	b.City(49, 100, "Berlin", 3605000)
+	b.City(49, 101, "Hamburg", 1739117)
+	b.Country(354, "Iceland", nil, spanner.NullDate{})
+
+	mx, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(mx) != 4 {
+		t.Errorf("Build returned %d mutations, want 4", len(mx))
+	}
+	if got, want := strings.Join(*writes, ", "), "Countries Germany, Cities Berlin, Cities Hamburg, Countries Iceland"; got != want {
+		t.Errorf("rows written in order %q, want %q", got, want)
+	}
+}
+
+func TestMutationBuilderInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		desc  string
+		build func(b *mutationBuilder)
+		want  []string
+	}{
+		{
+			desc:  "country without ID",
+			build: func(b *mutationBuilder) { b.Country(0, "Germany", nil, spanner.NullDate{}) },
+			want:  []string{`country "Germany" has no ID`},
+		},
+		{
+			desc:  "country without name",
+			build: func(b *mutationBuilder) { b.Country(49, "", nil, spanner.NullDate{}) },
+			want:  []string{"country 49 has no name"},
+		},
+		{
+			desc:  "city without IDs and name",
+			build: func(b *mutationBuilder) { b.City(0, 0, "", 0) },
+			want:  []string{`city "" has no country ID`, `city "" in country 0 has no ID`, "city 0 in country 0 has no name"},
+		},
+		{
+			desc:  "negative population",
+			build: func(b *mutationBuilder) { b.City(49, 100, "Berlin", -1) },
+			want:  []string{"city 100 in country 49 has a negative population"},
+		},
+		{
+			desc: "errors of several rows",
+			build: func(b *mutationBuilder) {
+				b.Country(49, "", nil, spanner.NullDate{})
+				b.City(49, 100, "Berlin", 1)
+				b.City(49, 101, "", 1)
+			},
+			want: []string{"country 49 has no name", "city 101 in country 49 has no name"},
+		},
+	} {
+		write, _ := recordWrites()
+		b := newMutationBuilder(write)
+		tc.build(b)
+		mx, err := b.Build()
+		if err == nil {
+			t.Errorf("%s: Build succeeded with %d mutations, want an error", tc.desc, len(mx))
+			continue
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: Build error %q does not mention %q", tc.desc, err, want)
+			}
+		}
+	}
+}
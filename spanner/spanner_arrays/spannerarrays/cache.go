@@ -0,0 +1,198 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+// QueryCache is an in-memory cache of query results, keyed by the database and the SQL and
+// parameters of the query. It holds at most a fixed number of results, evicting the least
+// recently used one to make room for a new one, and forgets them once they are older than
+// its time to live. Every write through this package, such as InsertCity, LoadPresets or
+// ResetAllCityNames, makes all caches forget the results of the database written to; writes
+// of other programs are only seen once the cached results expire. It is safe for concurrent
+// use.
+//
+// Pass a QueryCache in QueryConfig.Cache to cache the results of QueryCountriesWithConfig.
+type QueryCache struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu sync.Mutex
+	// order holds the *cacheEntry values, the most recently used first, and entries maps
+	// their keys to their elements of order.
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// cacheEntry is a result held by a QueryCache. generation is the write generation of db
+// when the query started; the result is stale once the generation has changed.
+type cacheEntry struct {
+	key        string
+	db         string
+	generation uint64
+	value      interface{}
+	expires    time.Time
+}
+
+// NewQueryCache returns an empty cache holding at most capacity results for ttl each.
+func NewQueryCache(capacity int, ttl time.Duration) (*QueryCache, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("invalid cache capacity %d, must be positive", capacity)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("invalid cache time to live %v, must be positive", ttl)
+	}
+	return &QueryCache{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}, nil
+}
+
+// Get returns the result cached for key in the database db, and false if there is none, it
+// has expired or the database has been written to since.
+func (c *QueryCache) Get(db, key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := db + "\x00" + key
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) || entry.generation != writeGeneration(db) {
+		c.order.Remove(e)
+		delete(c.entries, k)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+// Put caches value as the result for key in the database db, replacing any previous one.
+func (c *QueryCache) Put(db, key string, value interface{}) {
+	c.put(db, key, value, writeGeneration(db))
+}
+
+// put is Put for a result read at the write generation of db.
+func (c *QueryCache) put(db, key string, value interface{}, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := db + "\x00" + key
+	entry := &cacheEntry{key: k, db: db, generation: generation, value: value, expires: c.now().Add(c.ttl)}
+	if e, ok := c.entries[k]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[k] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Invalidate removes every cached result.
+func (c *QueryCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = map[string]*list.Element{}
+}
+
+// Len returns the number of results held, including those which are stale but haven't been
+// looked up since.
+func (c *QueryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// countries returns the countries cached for key in the database db, or calls query and
+// caches the countries it returns. The caller gets its own copy of the countries, which it
+// may sort or modify.
+func (c *QueryCache) countries(db, key string, query func() ([]Country, error)) ([]Country, error) {
+	if v, ok := c.Get(db, key); ok {
+		return copyCountries(v.([]Country)), nil
+	}
+	// A write committed while the query runs may or may not be seen by it, so the result is
+	// cached as of the generation before the query.
+	generation := writeGeneration(db)
+	countries, err := query()
+	if err != nil {
+		return nil, err
+	}
+	c.put(db, key, copyCountries(countries), generation)
+	return countries, nil
+}
+
+// copyCountries returns a copy of countries which shares no slices with it.
+func copyCountries(countries []Country) []Country {
+	cp := make([]Country, len(countries))
+	for i, c := range countries {
+		cp[i] = c
+		cp[i].Colours = append([]spanner.NullString(nil), c.Colours...)
+		cp[i].Cities = append([]spanner.NullString(nil), c.Cities...)
+	}
+	return cp
+}
+
+// writeGenerations counts the writes made through this package to each database, by name.
+var writeGenerations = struct {
+	sync.Mutex
+	n map[string]uint64
+}{n: map[string]uint64{}}
+
+// writeGeneration returns the number of writes made to the database db so far.
+func writeGeneration(db string) uint64 {
+	writeGenerations.Lock()
+	defer writeGenerations.Unlock()
+	return writeGenerations.n[db]
+}
+
+// recordWrite counts a write to the database db, which makes the results cached for it stale.
+func recordWrite(db string) {
+	writeGenerations.Lock()
+	defer writeGenerations.Unlock()
+	writeGenerations.n[db]++
+}
+
+// invalidateCache makes the results cached for the database of client stale. The functions
+// writing rows defer it, so that the results are also dropped if the commit reports an
+// error after having been applied.
+func invalidateCache(client *spanner.Client) {
+	recordWrite(client.DatabaseName())
+}
+
+// cacheKey returns the key of the results of the query described by c: its SQL and
+// parameters, together with the settings which change the results without changing the SQL.
+func (c QueryConfig) cacheKey() string {
+	stmt := c.statement()
+	names := make([]string, 0, len(stmt.Params))
+	for name := range stmt.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(stmt.SQL)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\x00%s=%v", name, stmt.Params[name])
+	}
+	fmt.Fprintf(&b, "\x00limit=%d\x00staleness=%v\x00timestamp=%s", c.Limit, c.Staleness, c.ReadTimestamp.Format(time.RFC3339Nano))
+	return b.String()
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+// countingQuery is a fake query which counts how often it has been run.
+type countingQuery struct {
+	calls     int
+	countries []Country
+}
+
+func (q *countingQuery) run() ([]Country, error) {
+	q.calls++
+	return q.countries, nil
+}
+
+func TestQueryCacheCountries(t *testing.T) {
+	const db = "projects/p/instances/i/databases/cache-countries"
+	c, err := NewQueryCache(10, time.Minute)
+	if err != nil {
+		t.Fatalf("NewQueryCache: %v", err)
+	}
+	now := time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	berlin := spanner.NullString{StringVal: "Berlin", Valid: true}
+	q := &countingQuery{countries: []Country{{Name: "Germany", Cities: []spanner.NullString{berlin}}, {Name: "United Kingdom"}}}
+	key := QueryConfig{}.cacheKey()
+	for i := 0; i < 2; i++ {
+		countries, err := c.countries(db, key, q.run)
+		if err != nil || len(countries) != 2 {
+			t.Fatalf("countries = %v, %v, want the 2 countries", countries, err)
+		}
+	}
+	if q.calls != 1 {
+		t.Errorf("an identical query within the time to live ran %d queries, want 1", q.calls)
+	}
+
+	// The cached countries, including their cities, can't be changed by the callers.
+	countries, _ := c.countries(db, key, q.run)
+	countries[0].Name = "France"
+	countries[0].Cities[0].StringVal = "Paris"
+	if countries, _ := c.countries(db, key, q.run); countries[0].Name != "Germany" || countries[0].Cities[0] != berlin {
+		t.Errorf("modifying the returned countries changed the cached ones to %v", countries)
+	}
+
+	if _, err := c.countries(db, QueryConfig{Name: "Germany"}.cacheKey(), q.run); err != nil {
+		t.Fatalf("countries: %v", err)
+	}
+	if _, err := c.countries(db+"2", key, q.run); err != nil {
+		t.Fatalf("countries: %v", err)
+	}
+	if q.calls != 3 {
+		t.Errorf("queries with other parameters or in another database ran %d queries in total, want 3", q.calls)
+	}
+
+	recordWrite(db)
+	if _, err := c.countries(db, key, q.run); err != nil {
+		t.Fatalf("countries: %v", err)
+	}
+	if q.calls != 4 {
+		t.Errorf("a query after a write ran %d queries in total, want 4", q.calls)
+	}
+	if _, err := c.countries(db+"2", key, q.run); err != nil {
+		t.Fatalf("countries: %v", err)
+	}
+	if q.calls != 4 {
+		t.Errorf("a write to another database made the cached query run again, %d queries in total, want 4", q.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.countries(db, key, q.run); err != nil {
+		t.Fatalf("countries: %v", err)
+	}
+	if q.calls != 5 {
+		t.Errorf("a query after the time to live ran %d queries in total, want 5", q.calls)
+	}
+
+	c.Invalidate()
+	if _, err := c.countries(db, key, q.run); err != nil {
+		t.Fatalf("countries: %v", err)
+	}
+	if q.calls != 6 {
+		t.Errorf("a query after Invalidate ran %d queries in total, want 6", q.calls)
+	}
+}
+
+func TestQueryCacheEviction(t *testing.T) {
+	const db = "projects/p/instances/i/databases/cache-eviction"
+	c, err := NewQueryCache(2, time.Minute)
+	if err != nil {
+		t.Fatalf("NewQueryCache: %v", err)
+	}
+	c.Put(db, "a", 1)
+	c.Put(db, "b", 2)
+	c.Get(db, "a")
+	c.Put(db, "c", 3)
+	if _, ok := c.Get(db, "b"); ok {
+		t.Error("the least recently used result b is still cached")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(db, key); !ok {
+			t.Errorf("result %s was evicted, want it cached", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want the capacity 2", c.Len())
+	}
+}
+
+func TestNewQueryCacheInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		capacity int
+		ttl      time.Duration
+	}{{0, time.Minute}, {10, 0}, {10, -time.Second}} {
+		if _, err := NewQueryCache(tc.capacity, tc.ttl); err == nil {
+			t.Errorf("NewQueryCache(%d, %v) succeeded, want an error", tc.capacity, tc.ttl)
+		}
+	}
+}
+
+func TestQueryCountriesCached(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	c, err := NewQueryCache(10, time.Hour)
+	if err != nil {
+		t.Fatalf("NewQueryCache: %v", err)
+	}
+	query := func() []Country {
+		countries, err := QueryCountriesWithConfig(ctx, client, QueryConfig{Cache: c})
+		if err != nil {
+			t.Fatalf("QueryCountriesWithConfig: %v", err)
+		}
+		return countries
+	}
+	before := QueriesRun.Value()
+	query()
+	query()
+	if n := QueriesRun.Value() - before; n != 1 {
+		t.Errorf("two identical queries ran %d queries, want 1", n)
+	}
+
+	// The write through InsertCountry makes the cached result stale.
+	if err := InsertCountry(ctx, client, 33, "France"); err != nil {
+		t.Fatalf("InsertCountry: %v", err)
+	}
+	if countries := query(); len(countries) != 3 {
+		t.Errorf("QueryCountries after InsertCountry returned %d countries, want 3", len(countries))
+	}
+
+	// So does the partitioned DML of ResetAllCityNames.
+	if _, err := ResetAllCityNames(ctx, client, "Renamed"); err != nil {
+		t.Fatalf("ResetAllCityNames: %v", err)
+	}
+	before = QueriesRun.Value()
+	countries := query()
+	if n := QueriesRun.Value() - before; n != 1 {
+		t.Errorf("the query after ResetAllCityNames ran %d queries, want 1 missing the cache", n)
+	}
+	for _, country := range countries {
+		for _, city := range country.Cities {
+			if city.StringVal != "Renamed" {
+				t.Errorf("city %s of %s wasn't renamed by ResetAllCityNames", city, country.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+)
+
+// ChangeStreamName is the change stream, created with the tables, which records every
+// change to the Countries and Cities tables.
+const ChangeStreamName = "CountriesCitiesStream"
+
+// changeStreamHeartbeat is how often an idle partition reports a heartbeat record, which
+// keeps its query alive while nothing changes.
+const changeStreamHeartbeat = 10 * time.Second
+
+// changeStreamSQL reads one partition of the change stream. The initial query passes a NULL
+// partition token and returns the child partitions covering the whole key space.
+var changeStreamSQL = `SELECT ChangeRecord FROM READ_` + ChangeStreamName + ` (
+	start_timestamp => @start,
+	end_timestamp => @end,
+	partition_token => @token,
+	heartbeat_milliseconds => @heartbeat
+)`
+
+// DataChange is a row written, updated or deleted in a table of ChangeStreamName.
+type DataChange struct {
+	CommitTimestamp time.Time
+	// Table is the name of the modified table.
+	Table string
+	// ModType is INSERT, UPDATE or DELETE.
+	ModType string
+	// Keys and NewValues are JSON objects holding the primary key columns of the row and
+	// the values written to its other columns.
+	Keys, NewValues string
+}
+
+func (c DataChange) String() string {
+	return fmt.Sprintf("%s %s %s %s %s", c.CommitTimestamp.Format(time.RFC3339Nano), c.ModType, c.Table, c.Keys, c.NewValues)
+}
+
+// The records of a change stream query, each row holding a single ChangeRecord with one of
+// the three kinds of record set.
+type changeRecord struct {
+	DataChangeRecords      []*dataChangeRecord      `spanner:"data_change_record"`
+	HeartbeatRecords       []*heartbeatRecord       `spanner:"heartbeat_record"`
+	ChildPartitionsRecords []*childPartitionsRecord `spanner:"child_partitions_record"`
+}
+
+type dataChangeRecord struct {
+	CommitTimestamp                      time.Time     `spanner:"commit_timestamp"`
+	RecordSequence                       string        `spanner:"record_sequence"`
+	ServerTransactionID                  string        `spanner:"server_transaction_id"`
+	IsLastRecordInTransactionInPartition bool          `spanner:"is_last_record_in_transaction_in_partition"`
+	TableName                            string        `spanner:"table_name"`
+	ColumnTypes                          []*columnType `spanner:"column_types"`
+	Mods                                 []*mod        `spanner:"mods"`
+	ModType                              string        `spanner:"mod_type"`
+	ValueCaptureType                     string        `spanner:"value_capture_type"`
+	NumberOfRecordsInTransaction         int64         `spanner:"number_of_records_in_transaction"`
+	NumberOfPartitionsInTransaction      int64         `spanner:"number_of_partitions_in_transaction"`
+	TransactionTag                       string        `spanner:"transaction_tag"`
+	IsSystemTransaction                  bool          `spanner:"is_system_transaction"`
+}
+
+type columnType struct {
+	Name            string           `spanner:"name"`
+	Type            spanner.NullJSON `spanner:"type"`
+	IsPrimaryKey    bool             `spanner:"is_primary_key"`
+	OrdinalPosition int64            `spanner:"ordinal_position"`
+}
+
+type mod struct {
+	Keys      spanner.NullJSON `spanner:"keys"`
+	NewValues spanner.NullJSON `spanner:"new_values"`
+	OldValues spanner.NullJSON `spanner:"old_values"`
+}
+
+type heartbeatRecord struct {
+	Timestamp time.Time `spanner:"timestamp"`
+}
+
+type childPartitionsRecord struct {
+	StartTimestamp  time.Time         `spanner:"start_timestamp"`
+	RecordSequence  string            `spanner:"record_sequence"`
+	ChildPartitions []*childPartition `spanner:"child_partitions"`
+}
+
+type childPartition struct {
+	Token                 string   `spanner:"token"`
+	ParentPartitionTokens []string `spanner:"parent_partition_tokens"`
+}
+
+// WatchChanges reads the changes committed to ChangeStreamName between start and end and
+// calls handle with each of them. end may lie in the future, in which case WatchChanges
+// reports the changes as they are committed until then. handle is never called concurrently,
+// but the changes of different partitions, and so of different rows, can arrive out of
+// commit timestamp order. Only GoogleSQL databases are supported.
+//
+// A change stream is split into partitions which Spanner splits and merges over time. Each
+// partition is read by its own query, which ends with child partitions records naming the
+// partitions that continue it; WatchChanges starts a query for every child it has not seen
+// before, as a child merged from several parents is reported by each of them.
+func WatchChanges(ctx context.Context, client *spanner.Client, start, end time.Time, handle func(DataChange)) error {
+	var (
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	var readPartition func(token spanner.NullString, start time.Time) error
+	readPartition = func(token spanner.NullString, start time.Time) error {
+		it := client.Single().Query(gctx, spanner.Statement{
+			SQL: changeStreamSQL,
+			Params: map[string]interface{}{
+				"start":     start,
+				"end":       end,
+				"token":     token,
+				"heartbeat": changeStreamHeartbeat.Milliseconds(),
+			},
+		})
+		err := it.Do(func(row *spanner.Row) error {
+			var records []*changeRecord
+			if err := row.Column(0, &records); err != nil {
+				return err
+			}
+			for _, r := range records {
+				for _, dc := range r.DataChangeRecords {
+					mu.Lock()
+					for _, m := range dc.Mods {
+						handle(DataChange{
+							CommitTimestamp: dc.CommitTimestamp,
+							Table:           dc.TableName,
+							ModType:         dc.ModType,
+							Keys:            m.Keys.String(),
+							NewValues:       m.NewValues.String(),
+						})
+					}
+					mu.Unlock()
+				}
+				for _, cp := range r.ChildPartitionsRecords {
+					for _, child := range cp.ChildPartitions {
+						mu.Lock()
+						isNew := !seen[child.Token]
+						seen[child.Token] = true
+						mu.Unlock()
+						if isNew {
+							token, start := spanner.NullString{StringVal: child.Token, Valid: true}, cp.StartTimestamp
+							g.Go(func() error { return readPartition(token, start) })
+						}
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if token.Valid {
+				return fmt.Errorf("failed to read change stream partition %s: %w", token.StringVal, err)
+			}
+			return fmt.Errorf("failed to read change stream %s: %w", ChangeStreamName, err)
+		}
+		return nil
+	}
+	g.Go(func() error { return readPartition(spanner.NullString{}, start) })
+	return g.Wait()
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestWatchChanges(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	var changes []DataChange
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchChanges(ctx, client, start, start.Add(10*time.Second), func(c DataChange) {
+			changes = append(changes, c)
+		})
+	}()
+	if err := InsertCountry(ctx, client, 354, "Iceland"); err != nil {
+		t.Fatalf("InsertCountry: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("WatchChanges: %v", err)
+	}
+
+	// The presets were committed before start, so only the insert of Iceland is reported.
+	var found bool
+	for _, c := range changes {
+		if c.Table == "Countries" && c.ModType == "INSERT" && strings.Contains(c.Keys, "354") {
+			found = strings.Contains(c.NewValues, "Iceland")
+		}
+	}
+	if !found {
+		t.Errorf("WatchChanges reported %v, want the insert of Iceland into Countries", changes)
+	}
+}
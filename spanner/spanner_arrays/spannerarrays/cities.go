@@ -0,0 +1,173 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+)
+
+// City describes a single row of the Cities table.
+type City struct {
+	CountryID int64
+	CityID    int64
+	Name      spanner.NullString
+}
+
+// ListCities returns the cities of the country identified by countryID, ordered by CityId,
+// leaving out the soft-deleted ones. Cities is keyed by (CountryId, CityId), so the cities of
+// one country are read with a key range covering all keys which start with countryID,
+// without running a SQL query.
+func ListCities(ctx context.Context, client *spanner.Client, countryID int64) ([]City, error) {
+	return readCities(ctx, client.Single(), countryID)
+}
+
+// readCities is ListCities reading in txn.
+func readCities(ctx context.Context, txn *spanner.ReadOnlyTransaction, countryID int64) ([]City, error) {
+	keys := spanner.KeyRange{
+		Start: spanner.Key{countryID},
+		End:   spanner.Key{countryID},
+		Kind:  spanner.ClosedClosed,
+	}
+	cities := []City{}
+	err := iterate(ctx, txn.Read(ctx, "Cities", keys, []string{"CountryId", "CityId", "Name", "Deleted"}), func(i int, row *spanner.Row) error {
+		var (
+			city    City
+			deleted spanner.NullBool
+		)
+		if err := row.Columns(&city.CountryID, &city.CityID, &city.Name, &deleted); err != nil {
+			return fmt.Errorf("failed to decode city %d: %w", i, err)
+		}
+		// The Read API can't filter rows, so the deleted cities are skipped here.
+		if !deleted.Bool {
+			cities = append(cities, city)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cities, nil
+}
+
+// CountryWithCities describes a country together with the full records of its cities.
+type CountryWithCities struct {
+	Name   string
+	Cities []City
+}
+
+// QueryCountriesWithCityStructs returns every country together with its cities which aren't
+// soft-deleted. Instead of an array of names, the subquery uses SELECT AS STRUCT to return an
+// ARRAY<STRUCT<...>>, which ToStruct decodes into a slice of City.
+func QueryCountriesWithCityStructs(ctx context.Context, client *spanner.Client) ([]CountryWithCities, error) {
+	it := client.Single().Query(ctx, spanner.NewStatement(`
+		SELECT a.Name AS Name, ARRAY(
+			SELECT AS STRUCT b.CountryId, b.CityId, b.Name FROM Cities b
+			WHERE a.CountryId = b.CountryId`+notDeletedFilter+` ORDER BY b.CityId
+		) AS Cities FROM Countries a`))
+	defer it.Stop()
+
+	var countries []CountryWithCities
+	for i := 0; ; i++ {
+		row, err := it.Next()
+		if err == iterator.Done {
+			return countries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", i, err)
+		}
+
+		var country CountryWithCities
+		if err := row.ToStruct(&country); err != nil {
+			return nil, fmt.Errorf("failed to read row %d into CountryWithCities struct: %w", i, err)
+		}
+		countries = append(countries, country)
+	}
+}
+
+// CountryTree describes a row of Countries together with the rows of Cities interleaved in
+// it, mirroring the parent-child relationship of the two tables.
+type CountryTree struct {
+	CountryID int64
+	Name      string
+	Colours   []spanner.NullString
+	Cities    []City
+}
+
+// QueryCountryTrees returns every country with all its cities which aren't soft-deleted,
+// ordered by country ID and city ID. The cities of a country are stored next to the country
+// row because Cities is interleaved in Countries, so the ARRAY(SELECT AS STRUCT ...) subquery
+// reads them without a distributed join, and the whole tree is fetched by one query and
+// decoded by ToStruct, which fills in the nested []City.
+func QueryCountryTrees(ctx context.Context, client *spanner.Client) ([]CountryTree, error) {
+	return DecodeAll[CountryTree](client.Single().Query(ctx, spanner.NewStatement(`
+		SELECT a.CountryId, a.Name, a.Colours, ARRAY(
+			SELECT AS STRUCT b.CountryId, b.CityId, b.Name FROM Cities b
+			WHERE a.CountryId = b.CountryId`+notDeletedFilter+` ORDER BY b.CityId
+		) AS Cities FROM Countries a ORDER BY a.CountryId`)))
+}
+
+// FindCountriesByCityName returns the names of the countries which have a city called cityName,
+// not counting soft-deleted cities.
+//
+// Without an index Spanner would have to scan every row of Cities to find the matching names.
+// The CitiesByName index is sorted by Name, so the lookup reads only the matching index
+// entries; they contain the CountryId of each city because the index implicitly stores the
+// primary key of the indexed table. FORCE_INDEX makes the query use the index even where the
+// optimizer would not choose it by itself, e.g. in a freshly created database. The index
+// doesn't store Deleted, so Spanner reads it from the matching rows of Cities.
+func FindCountriesByCityName(ctx context.Context, client *spanner.Client, cityName string) ([]string, error) {
+	it := client.Single().Query(ctx, spanner.Statement{
+		SQL: `SELECT DISTINCT a.Name FROM Cities@{FORCE_INDEX=CitiesByName} b
+			JOIN Countries a ON a.CountryId = b.CountryId
+			WHERE b.Name = @name` + notDeletedFilter,
+		Params: map[string]interface{}{"name": cityName},
+	})
+	defer it.Stop()
+
+	names := []string{}
+	for {
+		row, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read results: %w", err)
+		}
+		var name string
+		if err := row.Column(0, &name); err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		names = append(names, name)
+	}
+}
+
+// QueryCityMetadata returns the JSON metadata of a city. The result is invalid (NULL) if no
+// metadata was stored for the city, and ErrCityNotFound is returned if the city doesn't exist
+// or has been soft-deleted.
+func QueryCityMetadata(ctx context.Context, client *spanner.Client, countryID, cityID int64) (spanner.NullJSON, error) {
+	row, err := client.Single().ReadRow(ctx, "Cities", spanner.Key{countryID, cityID}, []string{"Metadata", "Deleted"})
+	if spanner.ErrCode(err) == codes.NotFound {
+		return spanner.NullJSON{}, ErrCityNotFound
+	}
+	if err != nil {
+		return spanner.NullJSON{}, err
+	}
+	var (
+		metadata spanner.NullJSON
+		deleted  spanner.NullBool
+	)
+	if err := row.Columns(&metadata, &deleted); err != nil {
+		return spanner.NullJSON{}, fmt.Errorf("failed to decode metadata of city %d/%d: %w", countryID, cityID, err)
+	}
+	if deleted.Bool {
+		return spanner.NullJSON{}, ErrCityNotFound
+	}
+	return metadata, nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+func TestListCities(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cities, err := ListCities(ctx, client, 49)
+	if err != nil {
+		t.Fatalf("ListCities(49): %v", err)
+	}
+	want := []City{
+		{CountryID: 49, CityID: 100},
+		{CountryID: 49, CityID: 101},
+		{CountryID: 49, CityID: 102},
+	}
+	wantNames := []string{"Berlin", "Hamburg", "Dresden"}
+	if len(cities) != len(want) {
+		t.Fatalf("ListCities(49) returned %d cities, want %d", len(cities), len(want))
+	}
+	for i, c := range cities {
+		if c.CountryID != want[i].CountryID || c.CityID != want[i].CityID || c.Name.StringVal != wantNames[i] {
+			t.Errorf("ListCities(49)[%d] = %+v, want city %d %s", i, c, want[i].CityID, wantNames[i])
+		}
+	}
+
+	cities, err = ListCities(ctx, client, 1)
+	if err != nil {
+		t.Fatalf("ListCities(1): %v", err)
+	}
+	if cities == nil || len(cities) != 0 {
+		t.Errorf("ListCities(unknown country) = %v, want an empty slice", cities)
+	}
+}
+
+func TestQueryCountriesWithCityStructs(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	countries, err := QueryCountriesWithCityStructs(context.Background(), client)
+	if err != nil {
+		t.Fatalf("QueryCountriesWithCityStructs: %v", err)
+	}
+	var germany *CountryWithCities
+	for i := range countries {
+		if countries[i].Name == "Germany" {
+			germany = &countries[i]
+		}
+	}
+	if germany == nil {
+		t.Fatalf("QueryCountriesWithCityStructs = %v, want Germany among the results", countries)
+	}
+	want := []City{
+		{CountryID: 49, CityID: 100, Name: spanner.NullString{StringVal: "Berlin", Valid: true}},
+		{CountryID: 49, CityID: 101, Name: spanner.NullString{StringVal: "Hamburg", Valid: true}},
+		{CountryID: 49, CityID: 102, Name: spanner.NullString{StringVal: "Dresden", Valid: true}},
+	}
+	if len(germany.Cities) != len(want) {
+		t.Fatalf("Germany has %d cities, want %d", len(germany.Cities), len(want))
+	}
+	for i, c := range germany.Cities {
+		if c != want[i] {
+			t.Errorf("Germany city %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+}
+
+func TestFindCountriesByCityName(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	names, err := FindCountriesByCityName(ctx, client, "London")
+	if err != nil {
+		t.Fatalf("FindCountriesByCityName(London): %v", err)
+	}
+	if len(names) != 1 || names[0] != "United Kingdom" {
+		t.Errorf("FindCountriesByCityName(London) = %q, want [United Kingdom]", names)
+	}
+
+	names, err = FindCountriesByCityName(ctx, client, "Atlantis")
+	if err != nil {
+		t.Fatalf("FindCountriesByCityName(Atlantis): %v", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("FindCountriesByCityName(Atlantis) = %q, want none", names)
+	}
+}
+
+func TestQueryCityMetadata(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	metadata := spanner.NullJSON{Value: map[string]interface{}{"river": "Isar", "twinned": []interface{}{"Edinburgh"}}, Valid: true}
+	if _, err := InsertCityWithMetadata(ctx, client, 49, 103, "Munich", metadata); err != nil {
+		t.Fatalf("InsertCityWithMetadata: %v", err)
+	}
+	got, err := QueryCityMetadata(ctx, client, 49, 103)
+	if err != nil {
+		t.Fatalf("QueryCityMetadata(49, 103): %v", err)
+	}
+	if !got.Valid {
+		t.Fatal("QueryCityMetadata(49, 103) is NULL, want the inserted metadata")
+	}
+	gotJSON, _ := json.Marshal(got.Value)
+	wantJSON, _ := json.Marshal(metadata.Value)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("QueryCityMetadata(49, 103) = %s, want %s", gotJSON, wantJSON)
+	}
+
+	// Berlin is a preset without any metadata.
+	if got, err := QueryCityMetadata(ctx, client, 49, 100); err != nil || got.Valid {
+		t.Errorf("QueryCityMetadata(Berlin) = %v, %v, want NULL", got, err)
+	}
+	if _, err := QueryCityMetadata(ctx, client, 49, 999); err != ErrCityNotFound {
+		t.Errorf("QueryCityMetadata(missing city) error = %v, want ErrCityNotFound", err)
+	}
+}
+
+func TestQueryCountryTrees(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	trees, err := QueryCountryTrees(context.Background(), client)
+	if err != nil {
+		t.Fatalf("QueryCountryTrees: %v", err)
+	}
+	want := []struct {
+		id     int64
+		name   string
+		cities []string
+	}{
+		{44, "United Kingdom", []string{"London", "Liverpool", "Bristol", "Newcastle"}},
+		{49, "Germany", []string{"Berlin", "Hamburg", "Dresden"}},
+	}
+	if len(trees) != len(want) {
+		t.Fatalf("QueryCountryTrees returned %d countries, want %d", len(trees), len(want))
+	}
+	for i, w := range want {
+		tree := trees[i]
+		if tree.CountryID != w.id || tree.Name != w.name || len(tree.Colours) != 3 {
+			t.Errorf("country %d = %d %s with %d colours, want %d %s with 3", i, tree.CountryID, tree.Name, len(tree.Colours), w.id, w.name)
+		}
+		if len(tree.Cities) != len(w.cities) {
+			t.Errorf("%s has %d cities, want %d", w.name, len(tree.Cities), len(w.cities))
+			continue
+		}
+		for j, c := range tree.Cities {
+			if c.CountryID != w.id || c.CityID == 0 || c.Name.StringVal != w.cities[j] {
+				t.Errorf("%s city %d = %+v, want city %s of country %d", w.name, j, c, w.cities[j], w.id)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sampleDatabaseRE matches the IDs of the databases the tests of the sample create, test-
+// followed by the creation time in nanoseconds. They are left behind when a test run is
+// killed before it drops them.
+var sampleDatabaseRE = regexp.MustCompile("^test-[0-9]+$")
+
+// OldSampleDatabases returns the names of the databases of the instance projects/P/instances/I
+// which follow the naming convention of the sample's tests and were created more than
+// olderThan ago.
+func OldSampleDatabases(ctx context.Context, adminClient *database.DatabaseAdminClient, instance string, olderThan time.Duration) ([]string, error) {
+	cutoff := time.Now().Add(-olderThan)
+	var names []string
+	it := adminClient.ListDatabases(ctx, &adminpb.ListDatabasesRequest{Parent: instance})
+	for {
+		db, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list databases of %s: %v", instance, err)
+		}
+		// A database which is still being created has no creation time yet.
+		if !sampleDatabaseRE.MatchString(path.Base(db.Name)) || db.CreateTime == nil {
+			continue
+		}
+		if db.CreateTime.AsTime().Before(cutoff) {
+			names = append(names, db.Name)
+		}
+	}
+}
+
+// CleanupOldSampleDatabases drops the databases returned by OldSampleDatabases and returns
+// how many it dropped. Databases which are dropped concurrently by someone else don't count
+// and aren't an error.
+func CleanupOldSampleDatabases(ctx context.Context, adminClient *database.DatabaseAdminClient, instance string, olderThan time.Duration) (int, error) {
+	names, err := OldSampleDatabases(ctx, adminClient, instance, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	dropped := 0
+	for _, db := range names {
+		err := RemoveDatabase(ctx, adminClient, db)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return dropped, fmt.Errorf("failed to drop %s: %v", db, err)
+		}
+		slog.InfoContext(ctx, "old sample database dropped", "database", db)
+		dropped++
+	}
+	return dropped, nil
+}
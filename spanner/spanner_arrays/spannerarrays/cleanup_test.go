@@ -0,0 +1,105 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeDatabases is a database admin server getting, listing and dropping the databases it
+// holds. If getErr is set, GetDatabase fails with it.
+type fakeDatabases struct {
+	adminpb.UnimplementedDatabaseAdminServer
+
+	mu        sync.Mutex
+	databases []*adminpb.Database
+	dropped   []string
+	getErr    error
+}
+
+func (f *fakeDatabases) GetDatabase(ctx context.Context, req *adminpb.GetDatabaseRequest) (*adminpb.Database, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	for _, db := range f.databases {
+		if db.Name == req.Name {
+			return db, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "database %s not found", req.Name)
+}
+
+func (f *fakeDatabases) ListDatabases(ctx context.Context, req *adminpb.ListDatabasesRequest) (*adminpb.ListDatabasesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &adminpb.ListDatabasesResponse{Databases: f.databases}, nil
+}
+
+func (f *fakeDatabases) DropDatabase(ctx context.Context, req *adminpb.DropDatabaseRequest) (*emptypb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, db := range f.databases {
+		if db.Name == req.Database {
+			f.databases = append(f.databases[:i], f.databases[i+1:]...)
+			f.dropped = append(f.dropped, req.Database)
+			return &emptypb.Empty{}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "database %s not found", req.Database)
+}
+
+func TestCleanupOldSampleDatabases(t *testing.T) {
+	const instance = "projects/p/instances/i"
+	now := time.Now()
+	created := func(id string, age time.Duration) *adminpb.Database {
+		return &adminpb.Database{Name: instance + "/databases/" + id, CreateTime: timestamppb.New(now.Add(-age))}
+	}
+	fake := &fakeDatabases{databases: []*adminpb.Database{
+		created("test-1", 72*time.Hour),
+		created("test-2", 25*time.Hour),
+		created("test-3", time.Hour),
+		created("production", 72*time.Hour),
+		created("test-old-but-not-sample", 72*time.Hour),
+		{Name: instance + "/databases/test-4"},
+	}}
+	admin, cleanup := newFakeAdminClient(t, fake)
+	defer cleanup()
+	ctx := context.Background()
+
+	old, err := OldSampleDatabases(ctx, admin, instance, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("OldSampleDatabases: %v", err)
+	}
+	want := instance + "/databases/test-1," + instance + "/databases/test-2"
+	if got := strings.Join(old, ","); got != want {
+		t.Errorf("OldSampleDatabases = %s, want %s", got, want)
+	}
+	if len(fake.dropped) != 0 {
+		t.Errorf("OldSampleDatabases dropped %q, want nothing dropped", fake.dropped)
+	}
+
+	n, err := CleanupOldSampleDatabases(ctx, admin, instance, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupOldSampleDatabases: %v", err)
+	}
+	if n != 2 || strings.Join(fake.dropped, ",") != want {
+		t.Errorf("CleanupOldSampleDatabases dropped %d: %q, want %s", n, fake.dropped, want)
+	}
+	if n, err := CleanupOldSampleDatabases(ctx, admin, instance, 24*time.Hour); err != nil || n != 0 {
+		t.Errorf("second CleanupOldSampleDatabases = %d, %v, want nothing left to drop", n, err)
+	}
+}
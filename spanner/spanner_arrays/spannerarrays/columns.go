@@ -0,0 +1,51 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"strings"
+)
+
+// countryColumns maps the columns which ColumnsSQL can select to their expressions. Cities
+// is the array of the names of the cities of each country, like in the country queries.
+var countryColumns = map[string]string{
+	"CountryId": "a.CountryId",
+	"Name":      "a.Name",
+	"Colours":   "a.Colours",
+	"Cities":    "ARRAY(SELECT b.Name FROM Cities b WHERE a.CountryId = b.CountryId" + notDeletedFilter + " ORDER BY b.Name)",
+}
+
+// CountryColumns lists the columns accepted by ColumnsSQL, in the order of the schema.
+var CountryColumns = []string{"CountryId", "Name", "Colours", "Cities"}
+
+// ColumnsSQL returns a query selecting the given columns of every country, ordered by name.
+// Column names are matched case-insensitively against CountryColumns, and only the
+// expressions of known columns end up in the query, so a column list taken from user input
+// can't inject SQL.
+func ColumnsSQL(columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("no columns selected, valid columns are: %s", strings.Join(CountryColumns, ", "))
+	}
+	var selects []string
+	for _, c := range columns {
+		name, ok := canonicalColumn(strings.TrimSpace(c))
+		if !ok {
+			return "", fmt.Errorf("unknown column %q, valid columns are: %s", c, strings.Join(CountryColumns, ", "))
+		}
+		selects = append(selects, fmt.Sprintf("%s AS %s", countryColumns[name], name))
+	}
+	return fmt.Sprintf("SELECT %s FROM Countries a ORDER BY a.Name", strings.Join(selects, ", ")), nil
+}
+
+// canonicalColumn returns the name in CountryColumns which equals c ignoring case.
+func canonicalColumn(c string) (string, bool) {
+	for _, name := range CountryColumns {
+		if strings.EqualFold(c, name) {
+			return name, true
+		}
+	}
+	return "", false
+}
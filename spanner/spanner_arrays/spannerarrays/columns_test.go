@@ -0,0 +1,68 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+func TestColumnsSQL(t *testing.T) {
+	sql, err := ColumnsSQL([]string{"name", " Cities"})
+	if err != nil {
+		t.Fatalf("ColumnsSQL(name, Cities): %v", err)
+	}
+	if !strings.HasPrefix(sql, "SELECT a.Name AS Name, ARRAY(") {
+		t.Errorf("ColumnsSQL(name, Cities) = %q, want it to select Name and then Cities", sql)
+	}
+
+	for _, columns := range [][]string{
+		{"DROP TABLE Countries"},
+		{"Name", "Name FROM Countries; DROP TABLE Countries; --"},
+		{"Population"},
+		{},
+	} {
+		sql, err := ColumnsSQL(columns)
+		if err == nil {
+			t.Errorf("ColumnsSQL(%q) = %q, want an error", columns, sql)
+			continue
+		}
+		if !strings.Contains(err.Error(), "valid columns are: CountryId, Name, Colours, Cities") {
+			t.Errorf("ColumnsSQL(%q) = %v, want an error listing the valid columns", columns, err)
+		}
+	}
+}
+
+func TestColumnsSQLName(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	sql, err := ColumnsSQL([]string{"Name"})
+	if err != nil {
+		t.Fatalf("ColumnsSQL(Name): %v", err)
+	}
+	var names []string
+	err = iterate(ctx, client.Single().Query(ctx, spanner.NewStatement(sql)), func(i int, row *spanner.Row) error {
+		if got := strings.Join(row.ColumnNames(), ","); got != "Name" {
+			t.Errorf("columns = %s, want Name", got)
+		}
+		var name string
+		if err := row.Column(0, &name); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("query %q: %v", sql, err)
+	}
+	if got, want := strings.Join(names, ", "), "Germany, United Kingdom"; got != want {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+// demoCountryID is the country DemoStrongRead adds a city to: Germany in the presets.
+const demoCountryID int64 = 49
+
+// demoStaleness is how stale the read of DemoStrongRead which isn't strong may be.
+const demoStaleness = 10 * time.Second
+
+// StrongReadDemo is the outcome of DemoStrongRead.
+type StrongReadDemo struct {
+	// CityID identifies the city inserted into the country demoCountryID.
+	CityID int64
+
+	// CommitTimestamp is the commit timestamp of the insert.
+	CommitTimestamp time.Time
+
+	// StrongRead reports whether the strong read found the city. It always does.
+	StrongRead bool
+
+	// StaleRead reports whether the bounded stale read found the city, which depends on
+	// whether Spanner chose to read at a timestamp before or after the commit.
+	StaleRead bool
+}
+
+// DemoStrongRead shows Spanner's read-your-writes consistency. It inserts a new city, then
+// reads it back once with a strong single-use read, which always sees every write committed
+// before it started, and once with a read which may be up to 10 seconds stale, which may or
+// may not see the city. Both results are logged together with the commit timestamp.
+func DemoStrongRead(ctx context.Context, client *spanner.Client) (StrongReadDemo, error) {
+	defer invalidateCache(client)
+	var demo StrongReadDemo
+	commitTS, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		// Reading the existing cities in the transaction makes sure no other writer picks
+		// the same ID between the read and the commit.
+		demo.CityID = 1
+		err := iterate(ctx, txn.Read(ctx, "Cities", spanner.Key{demoCountryID}.AsPrefix(), []string{"CityId"}), func(i int, row *spanner.Row) error {
+			var id int64
+			if err := row.Column(0, &id); err != nil {
+				return err
+			}
+			if id >= demo.CityID {
+				demo.CityID = id + 1
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return txn.BufferWrite([]*spanner.Mutation{
+			spanner.Insert("Cities", []string{"CountryId", "CityId", "Name", "Population", "LastModified"},
+				[]interface{}{demoCountryID, demo.CityID, "Consistency Demo", 0, spanner.CommitTimestamp}),
+		})
+	})
+	if err != nil {
+		return StrongReadDemo{}, fmt.Errorf("failed to insert city: %v", err)
+	}
+	demo.CommitTimestamp = commitTS
+	slog.InfoContext(ctx, "city written", "country", demoCountryID, "city", demo.CityID, "commit_timestamp", commitTS)
+
+	key := spanner.Key{demoCountryID, demo.CityID}
+	if demo.StrongRead, err = cityVisible(ctx, client.Single(), key); err != nil {
+		return StrongReadDemo{}, fmt.Errorf("strong read failed: %w", err)
+	}
+	slog.InfoContext(ctx, "strong read", "city", demo.CityID, "found", demo.StrongRead)
+
+	stale := client.Single().WithTimestampBound(spanner.MaxStaleness(demoStaleness))
+	if demo.StaleRead, err = cityVisible(ctx, stale, key); err != nil {
+		return StrongReadDemo{}, fmt.Errorf("stale read failed: %w", err)
+	}
+	slog.InfoContext(ctx, "stale read", "city", demo.CityID, "found", demo.StaleRead, "max_staleness", demoStaleness)
+	return demo, nil
+}
+
+// cityVisible reports whether txn sees the city with the given key.
+func cityVisible(ctx context.Context, txn *spanner.ReadOnlyTransaction, key spanner.Key) (bool, error) {
+	_, err := txn.ReadRow(ctx, "Cities", key, []string{"Name"})
+	if spanner.ErrCode(err) == codes.NotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
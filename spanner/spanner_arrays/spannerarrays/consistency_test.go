@@ -0,0 +1,33 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestDemoStrongRead(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Each run adds a new city, and every strong read must see the one just written.
+	var last int64
+	for i := 0; i < 3; i++ {
+		demo, err := DemoStrongRead(ctx, client)
+		if err != nil {
+			t.Fatalf("DemoStrongRead: %v", err)
+		}
+		if !demo.StrongRead {
+			t.Errorf("run %d: the strong read didn't see city %d committed at %v", i, demo.CityID, demo.CommitTimestamp)
+		}
+		if demo.CityID <= last {
+			t.Errorf("run %d inserted city %d, want a new ID after %d", i, demo.CityID, last)
+		}
+		last = demo.CityID
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+// InsertCountry adds a country without any cities or colours.
+func InsertCountry(ctx context.Context, client *spanner.Client, id int64, name string) error {
+	defer invalidateCache(client)
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert("Countries", []string{"CountryId", "Name", "Colours"}, []interface{}{id, name, []string{}}),
+	})
+	return err
+}
+
+// InsertCity adds a city to the country identified by countryID.
+func InsertCity(ctx context.Context, client *spanner.Client, countryID, cityID int64, name string) error {
+	_, err := InsertCityWithTimestamp(ctx, client, countryID, cityID, name)
+	return err
+}
+
+// InsertCityWithTimestamp is like InsertCity, but also returns the commit timestamp of the
+// insert. Spanner stores the same timestamp in the LastModified column of the new city.
+func InsertCityWithTimestamp(ctx context.Context, client *spanner.Client, countryID, cityID int64, name string) (time.Time, error) {
+	return InsertCityWithMetadata(ctx, client, countryID, cityID, name, spanner.NullJSON{})
+}
+
+// InsertCityWithMetadata is like InsertCityWithTimestamp, but also stores metadata in the
+// JSON column Metadata of the new city. An invalid metadata leaves the column NULL.
+func InsertCityWithMetadata(ctx context.Context, client *spanner.Client, countryID, cityID int64, name string, metadata spanner.NullJSON) (time.Time, error) {
+	defer invalidateCache(client)
+	return client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert("Cities", []string{"CountryId", "CityId", "Name", "Population", "LastModified", "Metadata"},
+			[]interface{}{countryID, cityID, name, 0, spanner.CommitTimestamp, metadata}),
+	}, spanner.TransactionTag("app=spannerarrays,action=insert-city"))
+}
+
+// UpsertCountry inserts a country, or overwrites its name and colours if it already exists.
+func UpsertCountry(ctx context.Context, client *spanner.Client, id int64, name string, colours []string) error {
+	defer invalidateCache(client)
+	if colours == nil {
+		colours = []string{}
+	}
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdateMap("Countries", map[string]interface{}{
+			"CountryId": id,
+			"Name":      name,
+			"Colours":   colours,
+		}),
+	})
+	return err
+}
+
+// UpsertCity inserts a city into the country identified by countryID, or overwrites its name
+// and population if it already exists.
+func UpsertCity(ctx context.Context, client *spanner.Client, countryID, cityID int64, name string, population int64) error {
+	defer invalidateCache(client)
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdateMap("Cities", map[string]interface{}{
+			"CountryId":    countryID,
+			"CityId":       cityID,
+			"Name":         name,
+			"Population":   population,
+			"LastModified": spanner.CommitTimestamp,
+		}),
+	})
+	return err
+}
+
+// UpdateCityName renames an existing city.
+func UpdateCityName(ctx context.Context, client *spanner.Client, countryID, cityID int64, name string) error {
+	defer invalidateCache(client)
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.Update("Cities", []string{"CountryId", "CityId", "Name"}, []interface{}{countryID, cityID, name}),
+	})
+	return err
+}
+
+// DeleteCity soft-deletes a city: it sets its Deleted column to true, so that the country
+// queries leave it out, but keeps the row, which can still be read or restored. Use
+// HardDeleteCity to remove the row itself.
+func DeleteCity(ctx context.Context, client *spanner.Client, countryID, cityID int64) error {
+	defer invalidateCache(client)
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.Update("Cities", []string{"CountryId", "CityId", "Deleted"}, []interface{}{countryID, cityID, true}),
+	})
+	return err
+}
+
+// HardDeleteCity removes a city.
+func HardDeleteCity(ctx context.Context, client *spanner.Client, countryID, cityID int64) error {
+	defer invalidateCache(client)
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.Delete("Cities", spanner.Key{countryID, cityID}),
+	})
+	return err
+}
+
+// DeleteCountry removes a country. Cities are interleaved in Countries with
+// ON DELETE CASCADE, so Spanner removes the cities of the country in the same commit.
+func DeleteCountry(ctx context.Context, client *spanner.Client, id int64) error {
+	defer invalidateCache(client)
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.Delete("Countries", spanner.Key{id}),
+	})
+	return err
+}
+
+// DeleteCitiesInRange removes the cities of the country identified by countryID whose IDs lie
+// between fromCityID and toCityID, and returns how many it removed. Both bounds are
+// inclusive: the range is closed on both ends (spanner.ClosedClosed), unlike the default
+// spanner.ClosedOpen, which would exclude toCityID.
+//
+// Delete mutations don't report how many rows they removed, so the rows in the range are
+// counted first, in the same read-write transaction as the delete, which keeps the count
+// exact even if other writers modify the range concurrently.
+func DeleteCitiesInRange(ctx context.Context, client *spanner.Client, countryID, fromCityID, toCityID int64) (count int, err error) {
+	defer invalidateCache(client)
+	keys := spanner.KeyRange{
+		Start: spanner.Key{countryID, fromCityID},
+		End:   spanner.Key{countryID, toCityID},
+		Kind:  spanner.ClosedClosed,
+	}
+	_, err = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		count = 0
+		err := txn.Read(ctx, "Cities", keys, []string{"CityId"}).Do(func(*spanner.Row) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return txn.BufferWrite([]*spanner.Mutation{spanner.Delete("Cities", keys)})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
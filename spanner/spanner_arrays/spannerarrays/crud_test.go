@@ -0,0 +1,301 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCRUD(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := InsertCountry(ctx, client, 33, "France"); err != nil {
+		t.Fatalf("InsertCountry: %v", err)
+	}
+	if err := InsertCity(ctx, client, 33, 300, "Paris"); err != nil {
+		t.Fatalf("InsertCity: %v", err)
+	}
+
+	cityName := func() string {
+		row, err := client.Single().ReadRow(ctx, "Cities", spanner.Key{33, 300}, []string{"Name"})
+		if err != nil {
+			t.Fatalf("ReadRow(Cities, 33, 300): %v", err)
+		}
+		var name string
+		if err := row.Column(0, &name); err != nil {
+			t.Fatalf("Column(Name): %v", err)
+		}
+		return name
+	}
+	if got := cityName(); got != "Paris" {
+		t.Errorf("city name after insert = %q, want Paris", got)
+	}
+
+	if err := UpdateCityName(ctx, client, 33, 300, "Lyon"); err != nil {
+		t.Fatalf("UpdateCityName: %v", err)
+	}
+	if got := cityName(); got != "Lyon" {
+		t.Errorf("city name after update = %q, want Lyon", got)
+	}
+
+	if err := DeleteCountry(ctx, client, 33); err != nil {
+		t.Fatalf("DeleteCountry: %v", err)
+	}
+	for _, tc := range []struct {
+		table string
+		key   spanner.Key
+	}{
+		{"Countries", spanner.Key{33}},
+		{"Cities", spanner.Key{33, 300}},
+	} {
+		if _, err := client.Single().ReadRow(ctx, tc.table, tc.key, []string{"Name"}); spanner.ErrCode(err) != codes.NotFound {
+			t.Errorf("ReadRow(%s, %v) after DeleteCountry: got err %v, want NotFound", tc.table, tc.key, err)
+		}
+	}
+}
+
+func TestInsertCityWithTimestamp(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	before := time.Now()
+	ts, err := InsertCityWithTimestamp(ctx, client, 49, 103, "Munich")
+	if err != nil {
+		t.Fatalf("InsertCityWithTimestamp: %v", err)
+	}
+
+	row, err := client.Single().ReadRow(ctx, "Cities", spanner.Key{49, 103}, []string{"LastModified"})
+	if err != nil {
+		t.Fatalf("ReadRow(Cities, 49, 103): %v", err)
+	}
+	var lastModified time.Time
+	if err := row.Column(0, &lastModified); err != nil {
+		t.Fatalf("Column(LastModified): %v", err)
+	}
+	if lastModified.IsZero() {
+		t.Fatal("LastModified is zero, want the commit timestamp")
+	}
+	if !lastModified.Equal(ts) {
+		t.Errorf("LastModified = %v, want the returned commit timestamp %v", lastModified, ts)
+	}
+	// Allow for clock skew between this machine and Spanner.
+	if d := lastModified.Sub(before); d < -time.Minute || d > time.Minute {
+		t.Errorf("LastModified = %v, want within a minute of %v", lastModified, before)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, name := range []string{"Frankreich", "France"} {
+		if err := UpsertCountry(ctx, client, 33, name, []string{"blue", "white", "red"}); err != nil {
+			t.Fatalf("UpsertCountry(%s): %v", name, err)
+		}
+		if err := UpsertCity(ctx, client, 33, 300, "Paris", 2161000); err != nil {
+			t.Fatalf("UpsertCity(Paris): %v", err)
+		}
+	}
+	countries, err := QueryCountriesByName(ctx, client, "France")
+	if err != nil {
+		t.Fatalf("QueryCountriesByName(France): %v", err)
+	}
+	if len(countries) != 1 || len(countries[0].Cities) != 1 || countries[0].Cities[0].StringVal != "Paris" {
+		t.Errorf("QueryCountriesByName(France) = %v, want France with only Paris", countries)
+	}
+}
+
+func TestDeleteCitiesInRange(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	n, err := DeleteCitiesInRange(ctx, client, 49, 100, 101)
+	if err != nil {
+		t.Fatalf("DeleteCitiesInRange(49, 100, 101): %v", err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteCitiesInRange(49, 100, 101) = %d, want 2", n)
+	}
+	cities, err := ListCities(ctx, client, 49)
+	if err != nil {
+		t.Fatalf("ListCities(49): %v", err)
+	}
+	if len(cities) != 1 || cities[0].Name.StringVal != "Dresden" {
+		t.Errorf("cities of Germany after deleting 100-101 = %v, want only Dresden", cities)
+	}
+}
+
+func TestDeleteCity(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	germanCities := func(includeDeleted bool) string {
+		countries, err := QueryCountriesWithConfig(ctx, client, QueryConfig{Name: "Germany", IncludeDeleted: includeDeleted})
+		if err != nil {
+			t.Fatalf("QueryCountriesWithConfig(IncludeDeleted: %v): %v", includeDeleted, err)
+		}
+		if len(countries) != 1 {
+			t.Fatalf("QueryCountriesWithConfig(Germany) returned %d countries, want 1", len(countries))
+		}
+		var names []string
+		for _, c := range countries[0].Cities {
+			names = append(names, c.StringVal)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	// Dresden is city 102 of Germany.
+	if err := DeleteCity(ctx, client, 49, 102); err != nil {
+		t.Fatalf("DeleteCity: %v", err)
+	}
+	if got, want := germanCities(false), "Berlin, Hamburg"; got != want {
+		t.Errorf("cities after the soft delete = %q, want %q", got, want)
+	}
+	if got, want := germanCities(true), "Berlin, Dresden, Hamburg"; got != want {
+		t.Errorf("cities including deleted ones = %q, want %q", got, want)
+	}
+	row, err := client.Single().ReadRow(ctx, "Cities", spanner.Key{49, 102}, []string{"Deleted"})
+	if err != nil {
+		t.Fatalf("ReadRow of the soft-deleted city: %v", err)
+	}
+	var deleted spanner.NullBool
+	if err := row.Column(0, &deleted); err != nil {
+		t.Fatalf("Column(Deleted): %v", err)
+	}
+	if !deleted.Valid || !deleted.Bool {
+		t.Errorf("Deleted of the soft-deleted city = %v, want true", deleted)
+	}
+
+	if err := HardDeleteCity(ctx, client, 49, 102); err != nil {
+		t.Fatalf("HardDeleteCity: %v", err)
+	}
+	if got, want := germanCities(true), "Berlin, Hamburg"; got != want {
+		t.Errorf("cities including deleted ones after the hard delete = %q, want %q", got, want)
+	}
+	if _, err := client.Single().ReadRow(ctx, "Cities", spanner.Key{49, 102}, []string{"Name"}); spanner.ErrCode(err) != codes.NotFound {
+		t.Errorf("ReadRow of the hard-deleted city = %v, want NotFound", err)
+	}
+}
+
+func TestDeletedCityIsHidden(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Dresden is city 102 of Germany, with a population of 486854.
+	if err := DeleteCity(ctx, client, 49, 102); err != nil {
+		t.Fatalf("DeleteCity: %v", err)
+	}
+
+	counts, err := CountCitiesPerCountry(ctx, client)
+	if err != nil {
+		t.Fatalf("CountCitiesPerCountry: %v", err)
+	}
+	if counts["Germany"] != 2 {
+		t.Errorf("CountCitiesPerCountry[Germany] = %d, want 2", counts["Germany"])
+	}
+	totals, err := TotalPopulationByCountry(ctx, client)
+	if err != nil {
+		t.Fatalf("TotalPopulationByCountry: %v", err)
+	}
+	if got, want := totals["Germany"], int64(3605000+1739117); got != want {
+		t.Errorf("TotalPopulationByCountry[Germany] = %d, want %d", got, want)
+	}
+	var cities, people int64
+	for name, n := range counts {
+		cities += n
+		people += totals[name]
+	}
+	avg, err := AverageCityPopulation(ctx, client)
+	if err != nil {
+		t.Fatalf("AverageCityPopulation: %v", err)
+	}
+	if want := float64(people) / float64(cities); avg != want {
+		t.Errorf("AverageCityPopulation = %v, want %v", avg, want)
+	}
+	n, err := ParallelCountCities(ctx, client)
+	if err != nil {
+		t.Fatalf("ParallelCountCities: %v", err)
+	}
+	if n != cities {
+		t.Errorf("ParallelCountCities = %d, want %d", n, cities)
+	}
+
+	listed, err := ListCities(ctx, client, 49)
+	if err != nil {
+		t.Fatalf("ListCities: %v", err)
+	}
+	for _, c := range listed {
+		if c.CityID == 102 {
+			t.Errorf("ListCities(49) = %v, want Dresden left out", listed)
+		}
+	}
+	structs, err := QueryCountriesWithCityStructs(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountriesWithCityStructs: %v", err)
+	}
+	for _, c := range structs {
+		for _, city := range c.Cities {
+			if city.Name.StringVal == "Dresden" {
+				t.Errorf("QueryCountriesWithCityStructs returned the deleted Dresden")
+			}
+		}
+	}
+	trees, err := QueryCountryTrees(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountryTrees: %v", err)
+	}
+	for _, c := range trees {
+		for _, city := range c.Cities {
+			if city.Name.StringVal == "Dresden" {
+				t.Errorf("QueryCountryTrees returned the deleted Dresden")
+			}
+		}
+	}
+	if names, err := FindCountriesByCityName(ctx, client, "Dresden"); err != nil || len(names) != 0 {
+		t.Errorf("FindCountriesByCityName(Dresden) = %v, %v, want no countries", names, err)
+	}
+	if _, err := QueryCityMetadata(ctx, client, 49, 102); err != ErrCityNotFound {
+		t.Errorf("QueryCityMetadata(deleted city) = %v, want ErrCityNotFound", err)
+	}
+
+	session := NewQuerySession(client)
+	defer session.Close()
+	for _, tc := range []struct {
+		name  string
+		query func() ([]Country, error)
+	}{
+		{"QueryCountries", func() ([]Country, error) { return QueryCountries(ctx, client) }},
+		{"QueryCountriesFlat", func() ([]Country, error) { return QueryCountriesFlat(ctx, client) }},
+		{"ReadCountries", func() ([]Country, error) { return ReadCountries(ctx, client) }},
+		{"QueryCountriesConcurrently", func() ([]Country, error) { return QueryCountriesConcurrently(ctx, client, 2) }},
+		{"QueryCountriesByIDs", func() ([]Country, error) { return QueryCountriesByIDs(ctx, client, []int64{49}) }},
+		{"QueryCountriesPage", func() ([]Country, error) { return QueryCountriesPage(ctx, client, 10, 0) }},
+		{"QuerySession.Countries", func() ([]Country, error) { return session.Countries(ctx) }},
+	} {
+		countries, err := tc.query()
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		for _, c := range countries {
+			if strings.Contains(c.String(), "Dresden") {
+				t.Errorf("%s returned %s, want the deleted Dresden left out", tc.name, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+// csvColumns are the columns of a file imported by ImportCitiesCSV. The Population column is
+// optional.
+var csvColumns = []string{"CountryId", "CityId", "Name", "Population"}
+
+// ImportCitiesCSV inserts or updates the cities listed in the CSV file path and returns the
+// number of cities imported. The first row of the file must be the header
+// CountryId,CityId,Name, optionally followed by Population, and every other row describes
+// one city. Cities without a population column get a population of 0. The countries of the
+// cities must already exist, because Cities is interleaved in Countries.
+//
+// The whole file is parsed before anything is written, so a malformed file imports nothing.
+// The mutations are then applied in batches, like LoadFromFile, and opts are passed to every
+// commit.
+func ImportCitiesCSV(ctx context.Context, client *spanner.Client, path string, opts ...spanner.ApplyOption) (int, error) {
+	defer invalidateCache(client)
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	cities, err := readCitiesCSV(f)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CSV file %s: %v", path, err)
+	}
+	var mx []*spanner.Mutation
+	for _, c := range cities {
+		mx = append(mx, spanner.InsertOrUpdateMap("Cities", map[string]interface{}{
+			"CountryId":    c.countryID,
+			"CityId":       c.cityID,
+			"Name":         c.name,
+			"Population":   c.population,
+			"LastModified": spanner.CommitTimestamp,
+		}))
+	}
+	if err := ApplyBatched(ctx, client, mx, DefaultBatchSize, opts...); err != nil {
+		return 0, err
+	}
+	return len(cities), nil
+}
+
+// csvCity is a city read from a CSV file.
+type csvCity struct {
+	countryID, cityID int64
+	name              string
+	population        int64
+}
+
+// readCitiesCSV parses the CSV file read from r. Errors name the row of the file, counting
+// the header as row 1.
+func readCitiesCSV(r io.Reader) ([]csvCity, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing header %s", strings.Join(csvColumns[:3], ","))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n := len(header); n < 3 || n > len(csvColumns) || strings.Join(header, ",") != strings.Join(csvColumns[:n], ",") {
+		return nil, fmt.Errorf("row 1: header is %q, want %s with an optional %s", strings.Join(header, ","), strings.Join(csvColumns[:3], ","), csvColumns[3])
+	}
+	// The reader checks that every row has as many fields as the header.
+	cr.FieldsPerRecord = len(header)
+
+	var cities []csvCity
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return cities, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var c csvCity
+		if c.countryID, err = strconv.ParseInt(record[0], 10, 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid CountryId %q", row, record[0])
+		}
+		if c.cityID, err = strconv.ParseInt(record[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid CityId %q", row, record[1])
+		}
+		c.name = record[2]
+		if len(record) > 3 {
+			if c.population, err = strconv.ParseInt(record[3], 10, 64); err != nil {
+				return nil, fmt.Errorf("row %d: invalid Population %q", row, record[3])
+			}
+		}
+		cities = append(cities, c)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestImportCitiesCSV(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	f, err := ioutil.TempFile("", "cities*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	// City 100 of Germany is a preset, so it is updated rather than inserted.
+	if _, err := f.WriteString("CountryId,CityId,Name\n49,100,Berlin-Mitte\n49,200,\"Frankfurt, am Main\"\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ImportCitiesCSV(ctx, client, f.Name())
+	if err != nil {
+		t.Fatalf("ImportCitiesCSV: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("ImportCitiesCSV imported %d cities, want 2", n)
+	}
+
+	cities, err := ListCities(ctx, client, 49)
+	if err != nil {
+		t.Fatalf("ListCities(49): %v", err)
+	}
+	names := map[int64]string{}
+	for _, c := range cities {
+		names[c.CityID] = c.Name.StringVal
+	}
+	if names[100] != "Berlin-Mitte" || names[200] != "Frankfurt, am Main" {
+		t.Errorf("cities of Germany after import = %v, want Berlin-Mitte (100) and Frankfurt, am Main (200)", names)
+	}
+}
+
+func TestReadCitiesCSV(t *testing.T) {
+	cities, err := readCitiesCSV(strings.NewReader("CountryId,CityId,Name,Population\n44,1,London,8900000\n"))
+	if err != nil {
+		t.Fatalf("readCitiesCSV: %v", err)
+	}
+	if want := (csvCity{countryID: 44, cityID: 1, name: "London", population: 8900000}); len(cities) != 1 || cities[0] != want {
+		t.Errorf("readCitiesCSV = %+v, want [%+v]", cities, want)
+	}
+
+	for _, tc := range []struct {
+		csv, want string
+	}{
+		{csv: "", want: "missing header"},
+		{csv: "44,1,London\n", want: "row 1: header"},
+		{csv: "CountryId,CityId,Name\n44,1,London\nUK,2,Leeds\n", want: `row 3: invalid CountryId "UK"`},
+		{csv: "CountryId,CityId,Name\n44,one,London\n", want: `row 2: invalid CityId "one"`},
+		{csv: "CountryId,CityId,Name,Population\n44,1,London,many\n", want: `row 2: invalid Population "many"`},
+		{csv: "CountryId,CityId,Name\n44,1\n", want: "wrong number of fields"},
+	} {
+		if _, err := readCitiesCSV(strings.NewReader(tc.csv)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("readCitiesCSV(%q) = %v, want an error containing %q", tc.csv, err, tc.want)
+		}
+	}
+}
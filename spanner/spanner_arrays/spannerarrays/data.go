@@ -0,0 +1,198 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/net/context"
+)
+
+// CountryData describes a country and its cities as stored in a data file, e.g.
+//
+//	[{"name": "Germany", "id": 49, "founded": "1871-01-18", "cities": [{"id": 100, "name": "Berlin"}]}]
+//
+// A missing or null founded leaves the founding date NULL.
+type CountryData struct {
+	ID      int64            `json:"id"`
+	Name    string           `json:"name"`
+	Colours []string         `json:"colours"`
+	Founded spanner.NullDate `json:"founded"`
+	Cities  []CityData       `json:"cities"`
+}
+
+// CityData describes a city inside a CountryData.
+type CityData struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Population int64  `json:"population"`
+}
+
+// presets is the demonstration data loaded by LoadPresets.
+var presets = []CountryData{
+	{
+		ID:      49,
+		Name:    "Germany",
+		Colours: []string{"black", "red", "gold"},
+		Founded: spanner.NullDate{Date: civil.Date{Year: 1871, Month: time.January, Day: 18}, Valid: true},
+		Cities: []CityData{
+			{ID: 100, Name: "Berlin", Population: 3605000},
+			{ID: 101, Name: "Hamburg", Population: 1739117},
+			{ID: 102, Name: "Dresden", Population: 486854},
+		},
+	},
+	{
+		ID:      44,
+		Name:    "United Kingdom",
+		Colours: []string{"white", "red", "blue"},
+		Founded: spanner.NullDate{Date: civil.Date{Year: 1801, Month: time.January, Day: 1}, Valid: true},
+		Cities: []CityData{
+			{ID: 200, Name: "London", Population: 8788000},
+			{ID: 201, Name: "Liverpool", Population: 465700},
+			{ID: 202, Name: "Bristol", Population: 428100},
+			{ID: 203, Name: "Newcastle", Population: 304636},
+		},
+	},
+}
+
+// LoadPresets inserts some demonstration data into the tables. opts are passed to every commit.
+func LoadPresets(ctx context.Context, db *spanner.Client, opts ...spanner.ApplyOption) (err error) {
+	mx, err := dataMutations(presets)
+	if err != nil {
+		return err
+	}
+	ctx, span := startSpan(ctx, "spannerarrays.LoadPresets", attribute.Int("mutations", len(mx)))
+	defer func() { endSpan(span, err) }()
+
+	if err := applyData(ctx, db, presets, mx, opts...); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "presets loaded", "database", db.DatabaseName(), "mutationCount", len(mx))
+	return nil
+}
+
+// UpsertPresets writes the demonstration data like LoadPresets, but overwrites rows which
+// already exist instead of failing with AlreadyExists, so it can be run repeatedly against
+// the same database. opts are passed to every commit.
+func UpsertPresets(ctx context.Context, db *spanner.Client, opts ...spanner.ApplyOption) (err error) {
+	mx, err := upsertMutations(presets)
+	if err != nil {
+		return err
+	}
+	ctx, span := startSpan(ctx, "spannerarrays.UpsertPresets", attribute.Int("mutations", len(mx)))
+	defer func() { endSpan(span, err) }()
+
+	if err := applyData(ctx, db, presets, mx, opts...); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "presets upserted", "database", db.DatabaseName(), "mutationCount", len(mx))
+	return nil
+}
+
+// LoadFromFile inserts the countries and cities described by the JSON file at path.
+// Nothing is written if the file contains duplicate country or city IDs, or rows with a
+// missing ID or name. opts are passed to every commit.
+func LoadFromFile(ctx context.Context, db *spanner.Client, path string, opts ...spanner.ApplyOption) (err error) {
+	ctx, span := startSpan(ctx, "spannerarrays.LoadFromFile", attribute.String("path", path))
+	defer func() { endSpan(span, err) }()
+
+	countries, err := readDataFile(path)
+	if err != nil {
+		return err
+	}
+	mx, err := dataMutations(countries)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	span.SetAttributes(attribute.Int("mutations", len(mx)))
+	if err := applyData(ctx, db, countries, mx, opts...); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "data file loaded", "database", db.DatabaseName(), "path", path, "mutationCount", len(mx))
+	return nil
+}
+
+// readDataFile parses and validates the JSON data file at path.
+func readDataFile(path string) ([]CountryData, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var countries []CountryData
+	if err := json.Unmarshal(b, &countries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if err := validateData(countries); err != nil {
+		return nil, fmt.Errorf("invalid data in %s: %v", path, err)
+	}
+	return countries, nil
+}
+
+// validateData checks that the country IDs, and the city IDs within each country, are unique.
+func validateData(countries []CountryData) error {
+	var dups []string
+	seenCountries := map[int64]bool{}
+	for _, c := range countries {
+		if seenCountries[c.ID] {
+			dups = append(dups, fmt.Sprintf("country %d", c.ID))
+		}
+		seenCountries[c.ID] = true
+
+		seenCities := map[int64]bool{}
+		for _, city := range c.Cities {
+			if seenCities[city.ID] {
+				dups = append(dups, fmt.Sprintf("city %d in country %d", city.ID, c.ID))
+			}
+			seenCities[city.ID] = true
+		}
+	}
+	if len(dups) > 0 {
+		return fmt.Errorf("duplicate IDs: %s", strings.Join(dups, ", "))
+	}
+	return nil
+}
+
+// dataMutations returns the mutations inserting countries and their cities.
+func dataMutations(countries []CountryData) ([]*spanner.Mutation, error) {
+	return writeMutations(countries, spanner.InsertMap)
+}
+
+// upsertMutations returns the mutations inserting countries and their cities, or updating
+// them if they already exist.
+func upsertMutations(countries []CountryData) ([]*spanner.Mutation, error) {
+	return writeMutations(countries, spanner.InsertOrUpdateMap)
+}
+
+// writeMutations returns the mutations writing countries and their cities with write.
+// The first len(countries) mutations write the countries, followed by those of all the
+// cities, so that every parent row is written before its interleaved children.
+func writeMutations(countries []CountryData, write func(table string, in map[string]interface{}) *spanner.Mutation) ([]*spanner.Mutation, error) {
+	b := newMutationBuilder(write)
+	for _, c := range countries {
+		b.Country(c.ID, c.Name, c.Colours, c.Founded)
+	}
+	for _, c := range countries {
+		for _, city := range c.Cities {
+			b.City(c.ID, city.ID, city.Name, city.Population)
+		}
+	}
+	return b.Build()
+}
+
+// applyData commits the mutations returned by writeMutations for countries. The countries
+// are committed before any of the cities, and the batches of each are committed up to
+// LoadConcurrency at a time.
+func applyData(ctx context.Context, db *spanner.Client, countries []CountryData, mx []*spanner.Mutation, opts ...spanner.ApplyOption) error {
+	groups := [][]*spanner.Mutation{mx[:len(countries)], mx[len(countries):]}
+	return ApplyBatchedConcurrently(ctx, db, groups, DefaultBatchSize, LoadConcurrency, opts...)
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestValidateData(t *testing.T) {
+	if err := validateData(presets); err != nil {
+		t.Errorf("validateData(presets): %v", err)
+	}
+
+	err := validateData([]CountryData{
+		{ID: 1, Name: "A", Cities: []CityData{{ID: 10, Name: "a"}, {ID: 10, Name: "b"}}},
+		{ID: 1, Name: "B"},
+	})
+	if err == nil {
+		t.Fatal("validateData with duplicates succeeded, want error")
+	}
+	for _, want := range []string{"country 1", "city 10 in country 1"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("validateData error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestLoadFromFile(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "spannerarrays")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.json")
+	data := `[{"name": "France", "id": 33, "cities": [{"id": 300, "name": "Paris"}, {"id": 301, "name": "Lyon"}]}]`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFromFile(ctx, client, path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+
+	countries, err := QueryCountriesByName(ctx, client, "France")
+	if err != nil {
+		t.Fatalf("QueryCountriesByName(France): %v", err)
+	}
+	if len(countries) != 1 || len(countries[0].Cities) != 2 {
+		t.Errorf("QueryCountriesByName(France) = %v, want France with 2 cities", countries)
+	}
+
+	dup := `[{"name": "Spain", "id": 34}, {"name": "Spain", "id": 34}]`
+	if err := ioutil.WriteFile(path, []byte(dup), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFromFile(ctx, client, path); err == nil {
+		t.Error("LoadFromFile with duplicate IDs succeeded, want error")
+	}
+}
+
+func TestUpsertPresetsTwice(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// newTestClient has already inserted the presets once.
+	for i := 0; i < 2; i++ {
+		if err := UpsertPresets(ctx, client); err != nil {
+			t.Fatalf("UpsertPresets #%d: %v", i+1, err)
+		}
+	}
+	counts, err := CountCitiesPerCountry(ctx, client)
+	if err != nil {
+		t.Fatalf("CountCitiesPerCountry: %v", err)
+	}
+	if len(counts) != 2 || counts["Germany"] != 3 || counts["United Kingdom"] != 4 {
+		t.Errorf("cities per country after upserting twice = %v, want Germany 3, United Kingdom 4", counts)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrDatabaseNotFound is returned by the queries when the database of the client doesn't
+// exist. spanner.NewClient doesn't check that, so it is only detected by the first query.
+var ErrDatabaseNotFound = errors.New("spannerarrays: database not found, create it first with CreateDatabase")
+
+// DecodeAll decodes every row of it into a T with Row.ToStruct, stops it and returns the
+// rows in order. Columns are matched to the fields of T by name, ignoring case, or by their
+// `spanner` struct tags. The result is empty rather than nil if it has no rows.
+func DecodeAll[T any](it *spanner.RowIterator) ([]T, error) {
+	return decodeAll[T](context.Background(), it)
+}
+
+// decodeAll is DecodeAll for any rowIterator. It returns ctx.Err() as soon as ctx is done,
+// even if the iterator still has buffered rows.
+func decodeAll[T any](ctx context.Context, it rowIterator) ([]T, error) {
+	out := []T{}
+	err := iterate(ctx, it, func(i int, row *spanner.Row) error {
+		var v T
+		if err := row.ToStruct(&v); err != nil {
+			return fmt.Errorf("failed to read row %d into %T: %w", i, v, err)
+		}
+		out = append(out, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// iterate calls f with the index and contents of every row of it, in order. It stops it
+// before returning in every case: when the rows are exhausted, ctx is done, reading a row
+// fails, f returns an error or f panics. A NotFound error of the iterator for the database
+// is returned wrapping ErrDatabaseNotFound; other NotFound errors, such as for a missing
+// table or index, are returned like any other error. Stopping the iterator cancels the
+// streaming RPC which returns the rows, so an early return doesn't leave the stream open on
+// the server until it times out, and discards the rows buffered by the client.
+func iterate(ctx context.Context, it rowIterator, f func(i int, row *spanner.Row) error) error {
+	defer it.Stop()
+
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if isDatabaseNotFound(err) {
+			return fmt.Errorf("%w: %w", ErrDatabaseNotFound, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row %d: %w", i, err)
+		}
+		RowsRead.Add(1)
+		if err := f(i, row); err != nil {
+			return err
+		}
+	}
+}
+
+// isDatabaseNotFound reports whether err is the NotFound error Spanner returns for a
+// database which doesn't exist. NotFound is also returned for other missing resources, such
+// as a table or an index read before the DDL creating it has run, which name the resource in
+// the message instead.
+func isDatabaseNotFound(err error) bool {
+	s, ok := status.FromError(err)
+	return ok && s.Code() == codes.NotFound && strings.Contains(s.Message(), "Database not found")
+}
+
+// limitIterator returns at most n rows of the wrapped iterator and then iterator.Done, as if
+// the query had no more rows. Once it is stopped, by iterate for example, the wrapped
+// iterator is stopped too, cancelling the rest of the stream, so the remaining rows are
+// never sent.
+type limitIterator struct {
+	rowIterator
+	n int
+}
+
+func (it *limitIterator) Next() (*spanner.Row, error) {
+	if it.n <= 0 {
+		return nil, iterator.Done
+	}
+	it.n--
+	return it.rowIterator.Next()
+}
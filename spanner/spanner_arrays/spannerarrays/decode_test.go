@@ -0,0 +1,219 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sliceIterator returns rows one by one, followed by err or iterator.Done.
+type sliceIterator struct {
+	rows    []*spanner.Row
+	err     error
+	stopped bool
+}
+
+func (it *sliceIterator) Next() (*spanner.Row, error) {
+	if len(it.rows) == 0 {
+		if it.err != nil {
+			return nil, it.err
+		}
+		return nil, iterator.Done
+	}
+	row := it.rows[0]
+	it.rows = it.rows[1:]
+	return row, nil
+}
+
+func (it *sliceIterator) Stop() { it.stopped = true }
+
+// newRows builds one row with the given columns for each element of values.
+func newRows(t *testing.T, columns []string, values ...[]interface{}) []*spanner.Row {
+	var rows []*spanner.Row
+	for _, v := range values {
+		row, err := spanner.NewRow(columns, v)
+		if err != nil {
+			t.Fatalf("NewRow(%v): %v", v, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestDecodeAllCountries(t *testing.T) {
+	it := &sliceIterator{rows: newRows(t, []string{"Name", "Cities", "Colours"},
+		[]interface{}{"Germany", []string{"Berlin", "Hamburg"}, []string{"black", "red", "gold"}},
+		[]interface{}{"Iceland", []string{}, []string{"blue"}},
+	)}
+	countries, err := decodeAll[Country](context.Background(), it)
+	if err != nil {
+		t.Fatalf("decodeAll[Country]: %v", err)
+	}
+	if len(countries) != 2 {
+		t.Fatalf("decodeAll[Country] = %d countries, want 2", len(countries))
+	}
+	if c := countries[0]; c.Name != "Germany" || len(c.Cities) != 2 || c.Cities[1].StringVal != "Hamburg" || len(c.Colours) != 3 {
+		t.Errorf("countries[0] = %+v, want Germany with 2 cities and 3 colours", c)
+	}
+	if c := countries[1]; c.Name != "Iceland" || len(c.Cities) != 0 {
+		t.Errorf("countries[1] = %+v, want Iceland without cities", c)
+	}
+	if !it.stopped {
+		t.Error("decodeAll did not stop the iterator")
+	}
+}
+
+func TestDecodeAllCities(t *testing.T) {
+	it := &sliceIterator{rows: newRows(t, []string{"CountryId", "CityId", "Name"},
+		[]interface{}{int64(49), int64(100), "Berlin"},
+		[]interface{}{int64(49), int64(101), "Hamburg"},
+	)}
+	cities, err := decodeAll[City](context.Background(), it)
+	if err != nil {
+		t.Fatalf("decodeAll[City]: %v", err)
+	}
+	want := []City{
+		{CountryID: 49, CityID: 100, Name: spanner.NullString{StringVal: "Berlin", Valid: true}},
+		{CountryID: 49, CityID: 101, Name: spanner.NullString{StringVal: "Hamburg", Valid: true}},
+	}
+	if len(cities) != len(want) {
+		t.Fatalf("decodeAll[City] = %d cities, want %d", len(cities), len(want))
+	}
+	for i := range want {
+		if cities[i] != want[i] {
+			t.Errorf("cities[%d] = %+v, want %+v", i, cities[i], want[i])
+		}
+	}
+}
+
+func TestDecodeAllEmptyAndErrors(t *testing.T) {
+	cities, err := decodeAll[City](context.Background(), &sliceIterator{})
+	if err != nil || cities == nil || len(cities) != 0 {
+		t.Errorf("decodeAll[City](no rows) = %v, %v, want an empty slice", cities, err)
+	}
+
+	injected := errors.New("injected failure")
+	it := &sliceIterator{rows: newRows(t, []string{"CountryId"}, []interface{}{int64(1)}), err: injected}
+	if _, err := decodeAll[City](context.Background(), it); err == nil || !strings.Contains(err.Error(), "row 1: injected failure") {
+		t.Errorf("decodeAll[City] with a failing iterator = %v, want the injected failure for row 1", err)
+	}
+	if !it.stopped {
+		t.Error("decodeAll did not stop the failing iterator")
+	}
+}
+
+func TestReadCountriesDatabaseNotFound(t *testing.T) {
+	it := &sliceIterator{err: status.Error(codes.NotFound, "Database not found: projects/p/instances/i/databases/d")}
+	_, err := readCountries(context.Background(), it)
+	if !errors.Is(err, ErrDatabaseNotFound) || !strings.Contains(err.Error(), "databases/d") {
+		t.Errorf("readCountries with a NotFound iterator = %v, want ErrDatabaseNotFound with the server message", err)
+	}
+
+	// A missing index is not a missing database.
+	it = &sliceIterator{err: status.Error(codes.NotFound, "Index not found: CitiesByName")}
+	_, err = readCountries(context.Background(), it)
+	if errors.Is(err, ErrDatabaseNotFound) || status.Code(err) != codes.NotFound || !strings.Contains(err.Error(), "CitiesByName") {
+		t.Errorf("readCountries with a missing index = %v, want the NotFound error for the index", err)
+	}
+}
+
+func TestQueryMissingDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := fmt.Sprintf("%s/databases/missing-%d", testInstance(t), time.Now().UnixNano())
+	client, err := spanner.NewClient(ctx, db, testOptions()...)
+	if err != nil {
+		// Some client versions already fail here.
+		t.Skipf("NewClient(%q) failed before the query: %v", db, err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if _, err := QueryCountries(ctx, client); !errors.Is(err, ErrDatabaseNotFound) {
+		t.Errorf("QueryCountries(missing database) = %v, want ErrDatabaseNotFound", err)
+	}
+}
+
+// countingIterator is a sliceIterator which counts the calls of Next.
+type countingIterator struct {
+	sliceIterator
+	nexts int
+}
+
+func (it *countingIterator) Next() (*spanner.Row, error) {
+	it.nexts++
+	return it.sliceIterator.Next()
+}
+
+func TestIterateEarlyReturn(t *testing.T) {
+	rows := newRows(t, []string{"CountryId"}, []interface{}{int64(1)}, []interface{}{int64(2)}, []interface{}{int64(3)})
+	stop := errors.New("stop after the first row")
+
+	it := &countingIterator{sliceIterator: sliceIterator{rows: rows}}
+	err := iterate(context.Background(), it, func(i int, row *spanner.Row) error { return stop })
+	if err != stop {
+		t.Errorf("iterate with a failing function = %v, want %v", err, stop)
+	}
+	if it.nexts != 1 {
+		t.Errorf("iterate read %d rows after the function failed on the first, want 1", it.nexts)
+	}
+	if !it.stopped {
+		t.Error("iterate did not stop the iterator after returning early")
+	}
+
+	it = &countingIterator{sliceIterator: sliceIterator{rows: rows}}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("iterate swallowed the panic of its function")
+			}
+		}()
+		iterate(context.Background(), it, func(int, *spanner.Row) error { panic("boom") })
+	}()
+	if !it.stopped {
+		t.Error("iterate did not stop the iterator when its function panicked")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it = &countingIterator{sliceIterator: sliceIterator{rows: rows}}
+	err = iterate(ctx, it, func(int, *spanner.Row) error {
+		cancel()
+		return nil
+	})
+	if err != context.Canceled || it.nexts != 1 || !it.stopped {
+		t.Errorf("iterate after cancelling its context = %v after %d rows (stopped: %v), want %v after 1 row and a stopped iterator", err, it.nexts, it.stopped, context.Canceled)
+	}
+}
+
+func TestLimitIterator(t *testing.T) {
+	it := &sliceIterator{rows: newRows(t, []string{"Name", "Cities", "Colours"},
+		[]interface{}{"Germany", []string{"Berlin"}, []string{"black"}},
+		[]interface{}{"Iceland", []string{}, []string{"blue"}},
+		[]interface{}{"Norway", []string{"Oslo"}, []string{"red"}},
+	)}
+	countries, err := readCountries(context.Background(), &limitIterator{rowIterator: it, n: 1})
+	if err != nil {
+		t.Fatalf("readCountries with a limit of 1: %v", err)
+	}
+	if len(countries) != 1 || countries[0].Name != "Germany" {
+		t.Errorf("readCountries with a limit of 1 = %v, want only Germany", countries)
+	}
+	if !it.stopped {
+		t.Error("the iterator was not stopped after the limit was reached")
+	}
+	if len(it.rows) != 2 {
+		t.Errorf("%d rows were left unread, want the 2 beyond the limit", len(it.rows))
+	}
+}
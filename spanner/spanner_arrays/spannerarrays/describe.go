@@ -0,0 +1,69 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// TableInfo describes a table of the sample schema as reported by INFORMATION_SCHEMA.
+type TableInfo struct {
+	Name    string
+	Columns []ColumnInfo
+}
+
+// ColumnInfo describes a single column of a table.
+type ColumnInfo struct {
+	Name string
+	// Type is the Spanner type of the column, such as STRING(MAX) or ARRAY<STRING(1024)>.
+	Type     string
+	Nullable bool
+}
+
+// describeSQL lists the columns of the Countries and Cities tables in their declared order.
+// User tables live in the empty schema in GoogleSQL and in public in PostgreSQL databases, and
+// unquoted PostgreSQL identifiers are folded to lower case, so the statement matches both
+// spellings and works in either dialect.
+const describeSQL = `
+	SELECT t.TABLE_NAME, c.COLUMN_NAME, c.SPANNER_TYPE, c.IS_NULLABLE
+	FROM INFORMATION_SCHEMA.TABLES AS t
+	JOIN INFORMATION_SCHEMA.COLUMNS AS c
+		ON c.TABLE_SCHEMA = t.TABLE_SCHEMA AND c.TABLE_NAME = t.TABLE_NAME
+	WHERE t.TABLE_SCHEMA IN ('', 'public') AND LOWER(t.TABLE_NAME) IN ('countries', 'cities')
+	ORDER BY t.TABLE_NAME, c.ORDINAL_POSITION`
+
+// DescribeSchema returns the columns of the Countries and Cities tables, which is useful to
+// verify that a migration applied with ApplyDDL had the intended effect. Tables are ordered
+// by name and their columns by position.
+func DescribeSchema(ctx context.Context, client *spanner.Client) ([]TableInfo, error) {
+	// Queries on INFORMATION_SCHEMA must run in a single-use, strong read-only transaction.
+	it := client.Single().Query(ctx, spanner.NewStatement(describeSQL))
+	defer it.Stop()
+
+	var tables []TableInfo
+	for {
+		row, err := it.Next()
+		if err == iterator.Done {
+			return tables, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+
+		var table, column, typ, nullable string
+		if err := row.Columns(&table, &column, &typ, &nullable); err != nil {
+			return nil, fmt.Errorf("failed to read column: %w", err)
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Name != table {
+			tables = append(tables, TableInfo{Name: table})
+		}
+		t := &tables[len(tables)-1]
+		t.Columns = append(t.Columns, ColumnInfo{Name: column, Type: typ, Nullable: nullable == "YES"})
+	}
+}
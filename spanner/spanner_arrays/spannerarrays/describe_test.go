@@ -0,0 +1,54 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestDescribeSchema(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	tables, err := DescribeSchema(context.Background(), client)
+	if err != nil {
+		t.Fatalf("DescribeSchema: %v", err)
+	}
+	want := []TableInfo{
+		{Name: "Cities", Columns: []ColumnInfo{
+			{Name: "CountryId", Type: "INT64"},
+			{Name: "CityId", Type: "INT64"},
+			{Name: "Name", Type: "STRING(MAX)", Nullable: true},
+			{Name: "Population", Type: "INT64"},
+			{Name: "LastModified", Type: "TIMESTAMP", Nullable: true},
+			{Name: "Metadata", Type: "JSON", Nullable: true},
+			{Name: "Deleted", Type: "BOOL", Nullable: true},
+		}},
+		{Name: "Countries", Columns: []ColumnInfo{
+			{Name: "CountryId", Type: "INT64"},
+			{Name: "Name", Type: "STRING(1024)"},
+			{Name: "Colours", Type: "ARRAY<STRING(1024)>"},
+			{Name: "Founded", Type: "DATE", Nullable: true},
+			{Name: "GDP", Type: "NUMERIC", Nullable: true},
+		}},
+	}
+	if len(tables) != len(want) {
+		t.Fatalf("DescribeSchema returned %d tables (%v), want %d", len(tables), tables, len(want))
+	}
+	for i, table := range want {
+		got := tables[i]
+		if got.Name != table.Name || len(got.Columns) != len(table.Columns) {
+			t.Errorf("table %d = %s with %d columns, want %s with %d", i, got.Name, len(got.Columns), table.Name, len(table.Columns))
+			continue
+		}
+		for j, col := range table.Columns {
+			if got.Columns[j] != col {
+				t.Errorf("%s column %d = %+v, want %+v", table.Name, j, got.Columns[j], col)
+			}
+		}
+	}
+}
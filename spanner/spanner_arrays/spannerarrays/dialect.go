@@ -0,0 +1,113 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+// Dialect is the SQL dialect of a database.
+type Dialect string
+
+const (
+	// GoogleSQL is the default dialect of Cloud Spanner databases.
+	GoogleSQL Dialect = "googlesql"
+
+	// PostgreSQL databases accept PostgreSQL-compatible DDL and queries, which use
+	// positional parameters ($1, $2, ...) instead of named ones.
+	PostgreSQL Dialect = "postgresql"
+)
+
+// ParseDialect returns the dialect called s, which must be "googlesql" or "postgresql".
+// The empty string means GoogleSQL.
+func ParseDialect(s string) (Dialect, error) {
+	switch d := Dialect(s); d {
+	case "":
+		return GoogleSQL, nil
+	case GoogleSQL, PostgreSQL:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown dialect %q, must be %s or %s", s, GoogleSQL, PostgreSQL)
+}
+
+// adminDialect returns the value of d used in CreateDatabaseRequest.
+func (d Dialect) adminDialect() adminpb.DatabaseDialect {
+	if d == PostgreSQL {
+		return adminpb.DatabaseDialect_POSTGRESQL
+	}
+	return adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL
+}
+
+// createStatement returns the CREATE DATABASE statement for a database called name. The
+// dialects differ in how they quote identifiers.
+func (d Dialect) createStatement(name string) string {
+	if d == PostgreSQL {
+		return fmt.Sprintf(`CREATE DATABASE "%s"`, name)
+	}
+	return fmt.Sprintf("CREATE DATABASE `%s`", name)
+}
+
+// defaultLeaderStatement returns the statement making leader the default leader region of
+// the database called name.
+func (d Dialect) defaultLeaderStatement(name, leader string) string {
+	if d == PostgreSQL {
+		return fmt.Sprintf(`ALTER DATABASE "%s" SET spanner.default_leader = '%s'`, name, leader)
+	}
+	return fmt.Sprintf("ALTER DATABASE `%s` SET OPTIONS (default_leader = '%s')", name, leader)
+}
+
+// schema returns the DDL statements creating the tables, index and change stream in dialect d.
+func (d Dialect) schema() []string {
+	// Founded and GDP are NULL if unknown, LastModified holds the commit timestamp of the
+	// inserting write and Deleted is true once a city has been soft-deleted. Unquoted
+	// PostgreSQL identifiers are case-insensitive, so both dialects share the column names.
+	if d == PostgreSQL {
+		return []string{
+			`CREATE TABLE Countries (
+				CountryId	bigint NOT NULL,
+				Name		varchar(1024) NOT NULL,
+				Colours		varchar(1024)[] NOT NULL,
+				Founded		date,
+				GDP		numeric,
+				PRIMARY KEY (CountryId)
+			)`,
+			`CREATE TABLE Cities (
+				CountryId	bigint NOT NULL,
+				CityId		bigint NOT NULL,
+				Name		text,
+				Population	bigint NOT NULL,
+				LastModified	spanner.commit_timestamp,
+				Metadata	jsonb,
+				Deleted		boolean,
+				PRIMARY KEY (CountryId, CityId)
+			) INTERLEAVE IN PARENT Countries ON DELETE CASCADE`,
+			`CREATE INDEX CitiesByName ON Cities(Name)`,
+			`CREATE CHANGE STREAM ` + ChangeStreamName + ` FOR Countries, Cities`,
+		}
+	}
+	return []string{
+		`CREATE TABLE Countries (
+			CountryId 	INT64 NOT NULL,
+			Name   		STRING(1024) NOT NULL,
+			Colours     ARRAY<STRING(1024)> NOT NULL,
+			Founded     DATE,
+			GDP         NUMERIC
+		) PRIMARY KEY (CountryId)`,
+		`CREATE TABLE Cities (
+			CountryId	INT64 NOT NULL,
+			CityId		INT64 NOT NULL,
+			Name			STRING(MAX),
+			Population  INT64 NOT NULL,
+			LastModified TIMESTAMP OPTIONS (allow_commit_timestamp=true),
+			Metadata    JSON,
+			Deleted     BOOL
+		) PRIMARY KEY (CountryId, CityId),
+		INTERLEAVE IN PARENT Countries ON DELETE CASCADE`,
+		`CREATE INDEX CitiesByName ON Cities(Name)`,
+		`CREATE CHANGE STREAM ` + ChangeStreamName + ` FOR Countries, Cities`,
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+func TestParseDialect(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Dialect
+	}{
+		{"", GoogleSQL},
+		{"googlesql", GoogleSQL},
+		{"postgresql", PostgreSQL},
+	} {
+		got, err := ParseDialect(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("ParseDialect(%q) = %q, %v, want %q", tc.in, got, err, tc.want)
+		}
+	}
+	if _, err := ParseDialect("mysql"); err == nil {
+		t.Error("ParseDialect(mysql) succeeded, want an error")
+	}
+}
+
+func TestQueryConfigStatementPostgreSQL(t *testing.T) {
+	stmt := QueryConfig{Name: "Germany", Dialect: PostgreSQL}.statement()
+	if got, want := stmt.Params["p1"], "Germany"; got != want {
+		t.Errorf("PostgreSQL statement param p1 = %v, want %q", got, want)
+	}
+	if _, ok := stmt.Params["name"]; ok {
+		t.Error("PostgreSQL statement uses the named parameter @name")
+	}
+
+	// The parameters are numbered in the order they are added.
+	stmt = QueryConfig{Name: "Germany", MaxCities: 2, Dialect: PostgreSQL}.statement()
+	if stmt.Params["p1"] != int64(2) || stmt.Params["p2"] != "Germany" {
+		t.Errorf("PostgreSQL statement params = %v, want p1 = 2 and p2 = Germany", stmt.Params)
+	}
+	if !strings.Contains(stmt.SQL, "LIMIT $1") || !strings.Contains(stmt.SQL, "a.Name = $2") {
+		t.Errorf("PostgreSQL statement %q, want LIMIT $1 and a.Name = $2", stmt.SQL)
+	}
+}
+
+func TestQueryCountriesPostgreSQL(t *testing.T) {
+	db := fmt.Sprintf("%s/databases/test-pg-%d", testInstance(t), time.Now().UnixNano())
+	ctx := context.Background()
+	admin := newAdminClient(t)
+	defer admin.Close()
+
+	if err := CreateDatabaseWithDialect(ctx, admin, db, PostgreSQL); err != nil {
+		t.Fatalf("CreateDatabaseWithDialect(%q, postgresql): %v", db, err)
+	}
+	defer func() {
+		if err := RemoveDatabase(ctx, admin, db); err != nil {
+			t.Errorf("RemoveDatabase(%q): %v", db, err)
+		}
+	}()
+	client, err := spanner.NewClient(ctx, db, testOptions()...)
+	if err != nil {
+		t.Fatalf("NewClient(%q): %v", db, err)
+	}
+	defer client.Close()
+	if err := LoadPresets(ctx, client); err != nil {
+		t.Fatalf("LoadPresets: %v", err)
+	}
+
+	countries, err := QueryCountriesWithConfig(ctx, client, QueryConfig{Name: "Germany", Dialect: PostgreSQL})
+	if err != nil {
+		t.Fatalf("QueryCountriesWithConfig(Germany, postgresql): %v", err)
+	}
+	if len(countries) != 1 || countries[0].Name != "Germany" || len(countries[0].Cities) != 3 {
+		t.Errorf("QueryCountriesWithConfig(Germany, postgresql) = %v, want Germany with 3 cities", countries)
+	}
+}
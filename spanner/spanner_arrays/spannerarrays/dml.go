@@ -0,0 +1,27 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+// ResetAllCityNames renames every city to newName with partitioned DML and returns the number
+// of rows it changed. Unlike a mutation or a DML statement in a read-write transaction,
+// partitioned DML is not limited by the size of a single commit, which makes it suitable for
+// updating whole tables.
+//
+// Spanner runs the statement independently in each partition and may retry it on some of
+// them, so a partitioned DML statement must be idempotent: applying it twice to a row has to
+// give the same result as applying it once. Setting a column to a constant is idempotent;
+// something like SET Population = Population + 1 is not.
+func ResetAllCityNames(ctx context.Context, client *spanner.Client, newName string) (int64, error) {
+	defer invalidateCache(client)
+	return client.PartitionedUpdate(ctx, spanner.Statement{
+		SQL:    "UPDATE Cities SET Name = @name WHERE true",
+		Params: map[string]interface{}{"name": newName},
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestResetAllCityNames(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	n, err := ResetAllCityNames(ctx, client, "Springfield")
+	if err != nil {
+		t.Fatalf("ResetAllCityNames: %v", err)
+	}
+	if n != 7 {
+		t.Errorf("ResetAllCityNames updated %d rows, want the 7 preset cities", n)
+	}
+
+	countries, err := FindCountriesByCityName(ctx, client, "Springfield")
+	if err != nil {
+		t.Fatalf("FindCountriesByCityName(Springfield): %v", err)
+	}
+	if len(countries) != 2 {
+		t.Errorf("countries with a Springfield after the reset = %q, want both presets", countries)
+	}
+}
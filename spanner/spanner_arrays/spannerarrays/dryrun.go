@@ -0,0 +1,53 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+)
+
+// PlannedMutation describes a mutation which LoadPresets or LoadFromFile would apply.
+type PlannedMutation struct {
+	Op    string
+	Table string
+	Key   spanner.Key
+}
+
+// String returns m in the form "insert Cities (49,100)".
+func (m PlannedMutation) String() string {
+	return fmt.Sprintf("%s %s %s", m.Op, m.Table, m.Key)
+}
+
+// PresetMutations returns the mutations LoadPresets applies, in order, without contacting
+// Spanner.
+func PresetMutations() []PlannedMutation {
+	return planMutations(presets)
+}
+
+// FileMutations returns the mutations LoadFromFile applies for the data file at path, in
+// order, without contacting Spanner.
+func FileMutations(path string) ([]PlannedMutation, error) {
+	countries, err := readDataFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return planMutations(countries), nil
+}
+
+// planMutations describes the mutations returned by dataMutations for countries.
+func planMutations(countries []CountryData) []PlannedMutation {
+	var planned []PlannedMutation
+	for _, c := range countries {
+		planned = append(planned, PlannedMutation{Op: "insert", Table: "Countries", Key: spanner.Key{c.ID}})
+	}
+	for _, c := range countries {
+		for _, city := range c.Cities {
+			planned = append(planned, PlannedMutation{Op: "insert", Table: "Cities", Key: spanner.Key{c.ID, city.ID}})
+		}
+	}
+	return planned
+}
@@ -0,0 +1,43 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPresetMutations(t *testing.T) {
+	planned := PresetMutations()
+	mx, err := dataMutations(presets)
+	if err != nil {
+		t.Fatalf("dataMutations(presets): %v", err)
+	}
+	if got, want := len(planned), len(mx); got != want {
+		t.Fatalf("PresetMutations returned %d mutations, want %d like LoadPresets", got, want)
+	}
+	if got, want := planned[0].String(), "insert Countries (49)"; got != want {
+		t.Errorf("first planned mutation = %q, want %q", got, want)
+	}
+	if got, want := planned[2].String(), "insert Cities (49,100)"; got != want {
+		t.Errorf("third planned mutation = %q, want %q", got, want)
+	}
+}
+
+func TestDatabaseDDL(t *testing.T) {
+	ddl, err := DatabaseDDL("projects/p/instances/i/databases/d", DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("DatabaseDDL: %v", err)
+	}
+	if got, want := ddl[0], "CREATE DATABASE `d`"; got != want {
+		t.Errorf("first statement = %q, want %q", got, want)
+	}
+	if len(ddl) != 5 || !strings.HasPrefix(ddl[3], "CREATE INDEX CitiesByName") || !strings.HasPrefix(ddl[4], "CREATE CHANGE STREAM") {
+		t.Errorf("DatabaseDDL = %q, want CREATE DATABASE, two tables, the index and the change stream", ddl)
+	}
+	if _, err := DatabaseDDL("d", DatabaseOptions{}); err == nil {
+		t.Error("DatabaseDDL(invalid name) succeeded, want an error")
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// EmulatorHostEnv is the environment variable naming the address of a Cloud Spanner emulator.
+const EmulatorHostEnv = "SPANNER_EMULATOR_HOST"
+
+// EmulatorOptions returns the client options which connect the database admin client and
+// the Spanner client to the emulator listening on host, e.g. "localhost:9010".
+// The emulator serves plain-text gRPC and does not check credentials, so authentication
+// is disabled and the connection is insecure.
+func EmulatorOptions(host string) []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(host),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	}
+}
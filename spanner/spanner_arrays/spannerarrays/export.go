@@ -0,0 +1,59 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ExportCountries writes countries to the file path as newline-delimited JSON, one object
+// per country encoded by Country.MarshalJSON, so that the results can be consumed by data
+// pipelines that read JSON lines. Nothing is written for an empty slice, leaving an empty
+// file.
+//
+// The file is written to a temporary file in the same directory, which is renamed to path
+// once it is complete, so readers never see a partially written export and a failed
+// export leaves any previous file at path in place.
+func ExportCountries(path string, countries []Country) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, c := range countries {
+		// Encode terminates every object with a newline.
+		if err := enc.Encode(c); err != nil {
+			return fmt.Errorf("failed to export %s: %v", c.Name, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", f.Name(), err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %v", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", f.Name(), err)
+	}
+	// TempFile creates the file readable only by its owner, but the export is meant to be
+	// read by other programs.
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+// readExport parses the newline-delimited JSON file written by ExportCountries.
+func readExport(t *testing.T, path string) []Country {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening export: %v", err)
+	}
+	defer f.Close()
+
+	countries := []Country{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var c Country
+		if err := json.Unmarshal(s.Bytes(), &c); err != nil {
+			t.Fatalf("parsing exported line %q: %v", s.Text(), err)
+		}
+		countries = append(countries, c)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	return countries
+}
+
+func TestExportCountries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "countries.ndjson")
+
+	countries := []Country{
+		{
+			Name:    "Germany",
+			Colours: []spanner.NullString{{StringVal: "black", Valid: true}, {StringVal: "red", Valid: true}},
+			Cities:  []spanner.NullString{{StringVal: "Berlin", Valid: true}, {}},
+		},
+		{Name: "Iceland", Colours: []spanner.NullString{{StringVal: "blue", Valid: true}}, Cities: []spanner.NullString{}},
+	}
+	if err := ExportCountries(path, countries); err != nil {
+		t.Fatalf("ExportCountries: %v", err)
+	}
+	if got := readExport(t, path); !reflect.DeepEqual(got, countries) {
+		t.Errorf("exported countries = %+v, want %+v", got, countries)
+	}
+
+	// Exporting again replaces the file, and an empty result leaves it empty.
+	if err := ExportCountries(path, nil); err != nil {
+		t.Fatalf("ExportCountries(nil): %v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 0 {
+		t.Errorf("export of no countries = %q, want an empty file", b)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("export directory has %d files, want only the export and no temporary files", len(files))
+	}
+}
+
+func TestExportCountriesMissingDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ExportCountries(filepath.Join(dir, "missing", "countries.ndjson"), nil); err == nil {
+		t.Error("ExportCountries into a missing directory succeeded, want an error")
+	}
+}
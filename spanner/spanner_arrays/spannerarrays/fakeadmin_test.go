@@ -0,0 +1,60 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"net"
+	"testing"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// newFakeAdminClient serves srv on a local port and returns a database admin client
+// connected to it, together with a function which closes the client and stops the server.
+// srv usually embeds adminpb.UnimplementedDatabaseAdminServer and overrides the methods a
+// test needs.
+func newFakeAdminClient(t *testing.T, srv adminpb.DatabaseAdminServer) (*database.DatabaseAdminClient, func()) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	s := grpc.NewServer()
+	adminpb.RegisterDatabaseAdminServer(s, srv)
+	go s.Serve(lis)
+
+	admin, err := database.NewDatabaseAdminClient(context.Background(),
+		option.WithEndpoint(lis.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	if err != nil {
+		s.Stop()
+		t.Fatalf("NewDatabaseAdminClient: %v", err)
+	}
+	return admin, func() {
+		admin.Close()
+		s.Stop()
+	}
+}
+
+// doneOperation returns a completed long-running operation whose result is resp.
+func doneOperation(t *testing.T, name string, resp proto.Message) *longrunningpb.Operation {
+	a, err := anypb.New(resp)
+	if err != nil {
+		t.Fatalf("anypb.New: %v", err)
+	}
+	return &longrunningpb.Operation{
+		Name:   name,
+		Done:   true,
+		Result: &longrunningpb.Operation_Response{Response: a},
+	}
+}
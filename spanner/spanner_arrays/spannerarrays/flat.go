@@ -0,0 +1,65 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// QueryCountriesFlat returns the same countries as QueryCountries, but reads them with a
+// plain join which returns one row per city, and groups the rows into countries in Go.
+//
+// Compared to the ARRAY(...) subquery, the join repeats the country columns in every row and
+// needs the rows ordered by country to be grouped, but it is plain SQL which every client
+// can consume. It is a LEFT JOIN, so a country without cities still returns a single row,
+// whose CityId is NULL. Like QueryCountries, it leaves out the soft-deleted cities and
+// orders the cities of each country by name.
+func QueryCountriesFlat(ctx context.Context, client *spanner.Client) ([]Country, error) {
+	// The filter is part of the join condition, not a WHERE clause, so that a country whose
+	// cities are all deleted is still returned.
+	it := client.Single().Query(ctx, spanner.NewStatement(`
+		SELECT a.CountryId, a.Name, a.Colours, a.Founded, b.CityId, b.Name
+		FROM Countries a LEFT JOIN Cities b ON a.CountryId = b.CountryId`+notDeletedFilter+`
+		ORDER BY a.Name, a.CountryId, `+CityOrderName.column()))
+	defer it.Stop()
+
+	var (
+		countries []Country
+		lastID    int64
+	)
+	for i := 0; ; i++ {
+		row, err := it.Next()
+		if err == iterator.Done {
+			return countries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", i, err)
+		}
+
+		var (
+			countryID int64
+			name      string
+			colours   []spanner.NullString
+			founded   spanner.NullDate
+			cityID    spanner.NullInt64
+			cityName  spanner.NullString
+		)
+		if err := row.Columns(&countryID, &name, &colours, &founded, &cityID, &cityName); err != nil {
+			return nil, fmt.Errorf("failed to decode row %d: %w", i, err)
+		}
+		if len(countries) == 0 || countryID != lastID {
+			countries = append(countries, Country{Name: name, Colours: colours, Founded: founded})
+			lastID = countryID
+		}
+		if cityID.Valid {
+			c := &countries[len(countries)-1]
+			c.Cities = append(c.Cities, cityName)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestQueryCountriesFlat(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// A country without cities must be returned by both queries.
+	if err := InsertCountry(ctx, client, 33, "France"); err != nil {
+		t.Fatalf("InsertCountry: %v", err)
+	}
+	// Neither query may return a soft-deleted city.
+	if err := DeleteCity(ctx, client, 49, 101); err != nil {
+		t.Fatalf("DeleteCity: %v", err)
+	}
+
+	grouped := func(countries []Country) map[string]string {
+		m := map[string]string{}
+		for _, c := range countries {
+			var cities []string
+			for _, city := range c.Cities {
+				cities = append(cities, city.String())
+			}
+			m[c.Name] = strings.Join(cities, ",")
+		}
+		return m
+	}
+
+	nested, err := QueryCountries(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+	flat, err := QueryCountriesFlat(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountriesFlat: %v", err)
+	}
+	want, got := grouped(nested), grouped(flat)
+	if len(got) != 3 || len(got) != len(want) {
+		t.Errorf("QueryCountriesFlat returned %d countries, QueryCountries %d, want 3 each", len(got), len(want))
+	}
+	for name, cities := range want {
+		if got[name] != cities {
+			t.Errorf("QueryCountriesFlat cities of %s = %q, want %q like QueryCountries", name, got[name], cities)
+		}
+	}
+	if strings.Contains(got["Germany"], "Hamburg") {
+		t.Errorf("QueryCountriesFlat cities of Germany = %q, want the deleted Hamburg left out", got["Germany"])
+	}
+}
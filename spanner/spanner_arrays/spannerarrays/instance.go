@@ -0,0 +1,74 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateInstance creates the instance projects/projectID/instances/instanceID with nodeCount
+// nodes and waits until it is ready. config is either the full name of an instance
+// configuration or a short one such as "regional-us-central1". If the instance already
+// exists, CreateInstance does nothing.
+//
+// Instances are billed while they exist, so remove them with DeleteInstance once they are
+// no longer needed.
+func CreateInstance(ctx context.Context, projectID, instanceID, config string, nodeCount int32, opts ...option.ClientOption) error {
+	adminClient, err := instance.NewInstanceAdminClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create instance admin client: %v", err)
+	}
+	defer adminClient.Close()
+
+	parent := "projects/" + projectID
+	if !strings.HasPrefix(config, "projects/") {
+		config = parent + "/instanceConfigs/" + config
+	}
+	name := parent + "/instances/" + instanceID
+	op, err := adminClient.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     parent,
+		InstanceId: instanceID,
+		Instance: &instancepb.Instance{
+			Name:        name,
+			Config:      config,
+			DisplayName: instanceID,
+			NodeCount:   nodeCount,
+		},
+	})
+	if err == nil {
+		_, err = op.Wait(ctx)
+	}
+	if status.Code(err) == codes.AlreadyExists {
+		slog.InfoContext(ctx, "instance already exists, reusing it", "instance", name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create instance %s: %v", name, err)
+	}
+	slog.InfoContext(ctx, "instance created", "instance", name, "config", config, "nodes", nodeCount)
+	return nil
+}
+
+// DeleteInstance deletes the instance projects/projectID/instances/instanceID together with
+// all of its databases.
+func DeleteInstance(ctx context.Context, projectID, instanceID string, opts ...option.ClientOption) error {
+	adminClient, err := instance.NewInstanceAdminClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create instance admin client: %v", err)
+	}
+	defer adminClient.Close()
+
+	name := fmt.Sprintf("projects/%s/instances/%s", projectID, instanceID)
+	return adminClient.DeleteInstance(ctx, &instancepb.DeleteInstanceRequest{Name: name})
+}
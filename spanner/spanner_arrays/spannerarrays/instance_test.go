@@ -0,0 +1,40 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// TestCreateInstance creates a real instance, which is slow and billed, so it only runs when
+// GOLANG_SAMPLES_SPANNER_CREATE_INSTANCE is set to the instance configuration to use.
+func TestCreateInstance(t *testing.T) {
+	config := os.Getenv("GOLANG_SAMPLES_SPANNER_CREATE_INSTANCE")
+	if config == "" {
+		t.Skip("Skipping instance creation test. Set GOLANG_SAMPLES_SPANNER_CREATE_INSTANCE to an instance config.")
+	}
+	projectID := strings.Split(testInstance(t), "/")[1]
+	instanceID := fmt.Sprintf("test-%d", time.Now().Unix())
+	ctx := context.Background()
+
+	if err := CreateInstance(ctx, projectID, instanceID, config, 1, testOptions()...); err != nil {
+		t.Fatalf("CreateInstance(%s): %v", instanceID, err)
+	}
+	defer func() {
+		if err := DeleteInstance(ctx, projectID, instanceID, testOptions()...); err != nil {
+			t.Errorf("DeleteInstance(%s): %v", instanceID, err)
+		}
+	}()
+
+	if err := CreateInstance(ctx, projectID, instanceID, config, 1, testOptions()...); err != nil {
+		t.Errorf("CreateInstance(existing instance): %v", err)
+	}
+}
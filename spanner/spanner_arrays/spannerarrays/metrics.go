@@ -0,0 +1,20 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import "expvar"
+
+// Counters of the work done by this package since the program started. They are published
+// with expvar, so a program which serves HTTP with the default mux exposes them as JSON at
+// /debug/vars.
+var (
+	// RowsRead counts the rows decoded from queries and reads.
+	RowsRead = expvar.NewInt("spannerarrays.rows_read")
+	// MutationsApplied counts the mutations committed by ApplyBatched,
+	// ApplyBatchedConcurrently and BatchWriteCountries, and so by the functions loading data.
+	MutationsApplied = expvar.NewInt("spannerarrays.mutations_applied")
+	// QueriesRun counts the country queries run.
+	QueriesRun = expvar.NewInt("spannerarrays.queries_run")
+)
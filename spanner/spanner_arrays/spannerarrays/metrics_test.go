@@ -0,0 +1,37 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestMetrics(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mutations, rows, queries := MutationsApplied.Value(), RowsRead.Value(), QueriesRun.Value()
+	if err := UpsertPresets(ctx, client); err != nil {
+		t.Fatalf("UpsertPresets: %v", err)
+	}
+	// 2 countries and 7 cities.
+	if got := MutationsApplied.Value() - mutations; got != 9 {
+		t.Errorf("MutationsApplied grew by %d while writing the presets, want 9", got)
+	}
+
+	countries, err := QueryCountries(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+	if got := RowsRead.Value() - rows; got != int64(len(countries)) || got != 2 {
+		t.Errorf("RowsRead grew by %d while querying %d countries, want 2", got, len(countries))
+	}
+	if got := QueriesRun.Value() - queries; got != 1 {
+		t.Errorf("QueriesRun grew by %d, want 1", got)
+	}
+}
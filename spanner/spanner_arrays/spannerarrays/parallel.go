@@ -0,0 +1,127 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
+)
+
+// ParallelCountCities counts the cities by scanning Cities in parallel partitions of a batch
+// read-only transaction. All partitions read from the same snapshot, so the total is
+// consistent even though the partitions run independently. Soft-deleted cities aren't counted.
+//
+// A COUNT(*) query can't be partitioned, because its result is computed over all rows, so each
+// partition scans the city keys and counts them itself. If one partition fails, the context of
+// the others is cancelled and the first error is returned.
+func ParallelCountCities(ctx context.Context, client *spanner.Client) (int64, error) {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin batch read-only transaction: %w", err)
+	}
+	defer txn.Close()
+	// Cleanup releases the session on the server, which Close alone doesn't.
+	defer txn.Cleanup(ctx)
+
+	partitions, err := txn.PartitionQuery(ctx, spanner.NewStatement("SELECT b.CityId FROM Cities b WHERE "+notDeleted), spanner.PartitionOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to partition query: %w", err)
+	}
+
+	var total int64
+	g, gctx := errgroup.WithContext(ctx)
+	for i, p := range partitions {
+		i, p := i, p
+		g.Go(func() error {
+			it := txn.Execute(gctx, p)
+			defer it.Stop()
+			var n int64
+			for {
+				_, err := it.Next()
+				if err == iterator.Done {
+					atomic.AddInt64(&total, n)
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("partition %d: %w", i, err)
+				}
+				n++
+			}
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// QueryCountriesConcurrently returns the same countries as QueryCountries, but reads the
+// cities of every country with a separate query, running at most concurrency of them at a
+// time. It exists to compare the two approaches: one query with an ARRAY subquery against a
+// fan-out of one small query per country. The cities are filtered and ordered like those of
+// QueryCountries, and all queries read from the same read-only transaction, so the result
+// is a consistent snapshot just like that of QueryCountries.
+func QueryCountriesConcurrently(ctx context.Context, client *spanner.Client, concurrency int) ([]Country, error) {
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("invalid concurrency %d, must be positive", concurrency)
+	}
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	var ids []int64
+	countries := []Country{}
+	it := txn.Query(ctx, spanner.NewStatement("SELECT CountryId, Name, Colours, Founded FROM Countries"))
+	err := it.Do(func(row *spanner.Row) error {
+		var (
+			id      int64
+			country Country
+		)
+		if err := row.Columns(&id, &country.Name, &country.Colours, &country.Founded); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		countries = append(countries, country)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read countries: %w", err)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, id := range ids {
+		i, id := i, id
+		g.Go(func() error {
+			it := txn.Query(gctx, spanner.Statement{
+				SQL:    "SELECT b.Name FROM Cities b WHERE b.CountryId = @id" + notDeletedFilter + " ORDER BY b.Name",
+				Params: map[string]interface{}{"id": id},
+			})
+			// Every goroutine writes only its own element, so no locking is needed.
+			cities := []spanner.NullString{}
+			err := it.Do(func(row *spanner.Row) error {
+				var name spanner.NullString
+				if err := row.Column(0, &name); err != nil {
+					return err
+				}
+				cities = append(cities, name)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to read cities of country %d: %w", id, err)
+			}
+			countries[i].Cities = cities
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return countries, nil
+}
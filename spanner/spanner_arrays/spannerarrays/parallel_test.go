@@ -0,0 +1,71 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestParallelCountCities(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	n, err := ParallelCountCities(context.Background(), client)
+	if err != nil {
+		t.Fatalf("ParallelCountCities: %v", err)
+	}
+	if n != 7 {
+		t.Errorf("ParallelCountCities = %d, want the 7 preset cities", n)
+	}
+}
+
+// countrySummary describes a country by its name, colours and city names, so that results
+// can be compared regardless of row order.
+func countrySummary(c Country) string {
+	return fmt.Sprintf("%s %v %v", c.Name, c.Colours, c.Cities)
+}
+
+func TestQueryCountriesConcurrently(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Both approaches must leave out the soft-deleted Hamburg.
+	if err := DeleteCity(ctx, client, 49, 101); err != nil {
+		t.Fatalf("DeleteCity: %v", err)
+	}
+	single, err := QueryCountries(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+	var want []string
+	for _, c := range single {
+		want = append(want, countrySummary(c))
+	}
+	sort.Strings(want)
+
+	for _, concurrency := range []int{1, 2, 16} {
+		countries, err := QueryCountriesConcurrently(ctx, client, concurrency)
+		if err != nil {
+			t.Fatalf("QueryCountriesConcurrently(%d): %v", concurrency, err)
+		}
+		var got []string
+		for _, c := range countries {
+			got = append(got, countrySummary(c))
+		}
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("QueryCountriesConcurrently(%d) = %v, want %v", concurrency, got, want)
+		}
+	}
+
+	if _, err := QueryCountriesConcurrently(ctx, client, 0); err == nil {
+		t.Error("QueryCountriesConcurrently(0) succeeded, want an error")
+	}
+}
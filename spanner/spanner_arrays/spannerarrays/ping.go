@@ -0,0 +1,26 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// Ping runs SELECT 1 on the database of client, to check that the database can be reached
+// and that the caller is allowed to query it. Because the client opens its connections
+// lazily, a wrong database name or missing permissions would otherwise only be reported by
+// the first real operation.
+func Ping(ctx context.Context, client *spanner.Client) error {
+	it := client.Single().Query(ctx, spanner.NewStatement("SELECT 1"))
+	defer it.Stop()
+	if _, err := it.Next(); err != nil && err != iterator.Done {
+		return fmt.Errorf("cannot reach Spanner: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+func TestPing(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	if err := Ping(context.Background(), client); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
+
+func TestPingMissingDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := fmt.Sprintf("%s/databases/missing-%d", testInstance(t), time.Now().UnixNano())
+	client, err := spanner.NewClient(ctx, db, testOptions()...)
+	if err != nil {
+		// Some client versions already fail here, which is just as clear.
+		t.Skipf("NewClient(%q) failed before Ping: %v", db, err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if err := Ping(ctx, client); err == nil || !strings.Contains(err.Error(), "cannot reach Spanner") {
+		t.Errorf("Ping(missing database) = %v, want a cannot reach Spanner error", err)
+	}
+}
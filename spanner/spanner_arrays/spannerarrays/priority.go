@@ -0,0 +1,31 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+)
+
+// ParsePriority returns the request priority called s, which must be "low", "medium" or
+// "high". The empty string means that no priority is set, which Spanner treats as high.
+//
+// Spanner schedules low priority requests after the medium and high ones when the instance is
+// busy, so batch work such as loading data can be kept from slowing down the queries serving
+// users.
+func ParsePriority(s string) (sppb.RequestOptions_Priority, error) {
+	switch s {
+	case "":
+		return sppb.RequestOptions_PRIORITY_UNSPECIFIED, nil
+	case "low":
+		return sppb.RequestOptions_PRIORITY_LOW, nil
+	case "medium":
+		return sppb.RequestOptions_PRIORITY_MEDIUM, nil
+	case "high":
+		return sppb.RequestOptions_PRIORITY_HIGH, nil
+	}
+	return sppb.RequestOptions_PRIORITY_UNSPECIFIED, fmt.Errorf("unknown priority %q, must be low, medium or high", s)
+}
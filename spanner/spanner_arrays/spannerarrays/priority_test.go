@@ -0,0 +1,35 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+)
+
+func TestParsePriority(t *testing.T) {
+	for s, want := range map[string]sppb.RequestOptions_Priority{
+		"":       sppb.RequestOptions_PRIORITY_UNSPECIFIED,
+		"low":    sppb.RequestOptions_PRIORITY_LOW,
+		"medium": sppb.RequestOptions_PRIORITY_MEDIUM,
+		"high":   sppb.RequestOptions_PRIORITY_HIGH,
+	} {
+		got, err := ParsePriority(s)
+		if err != nil {
+			t.Errorf("ParsePriority(%q): %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParsePriority(%q) = %v, want %v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"LOW", "urgent", "PRIORITY_LOW"} {
+		if _, err := ParsePriority(s); err == nil {
+			t.Errorf("ParsePriority(%q) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+)
+
+// QueryProfile is the execution plan and the statistics Spanner reports for a query.
+type QueryProfile struct {
+	Plan *sppb.QueryPlan
+
+	// Stats holds execution statistics such as "rows_scanned", "rows_returned" and
+	// "elapsed_time", keyed by name.
+	Stats map[string]interface{}
+}
+
+// ProfileCountries runs the query selected by cfg in profile mode and returns its plan
+// and statistics instead of the countries. This shows what the correlated ARRAY(...)
+// subquery costs: the plan has a separate branch which scans Cities for every country.
+func ProfileCountries(ctx context.Context, client *spanner.Client, cfg QueryConfig) (*QueryProfile, error) {
+	if err := cfg.validate(time.Now()); err != nil {
+		return nil, err
+	}
+	it := cfg.transaction(client).QueryWithStats(ctx, cfg.statement())
+	defer it.Stop()
+
+	// The plan and statistics are only available once all rows have been read.
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		_, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", i, err)
+		}
+	}
+	return &QueryProfile{Plan: it.QueryPlan, Stats: it.QueryStats}, nil
+}
+
+// ResultStats summarizes the execution of a query whose results were read in full.
+type ResultStats struct {
+	// RowsReturned and BytesReturned are the amount of data the query sent to the client.
+	RowsReturned, BytesReturned int64
+
+	// Elapsed is the time Spanner spent executing the query, not counting the network.
+	Elapsed time.Duration
+
+	// Plan and Stats are the raw plan and statistics as reported by Spanner.
+	Plan  *sppb.QueryPlan
+	Stats map[string]interface{}
+}
+
+// QueryCountriesWithStats is QueryCountries, also returning the statistics of the query's
+// execution. Unlike ProfileCountries it returns the countries, so it can be used for the
+// actual query.
+func QueryCountriesWithStats(ctx context.Context, client *spanner.Client) ([]Country, *ResultStats, error) {
+	it := client.Single().QueryWithStats(ctx, spanner.NewStatement(countriesSQL))
+	// The statistics are only reported with the last row, so they are available once
+	// readCountries has read every row.
+	countries, err := readCountries(ctx, it)
+	if err != nil {
+		return nil, nil, err
+	}
+	stats, err := resultStats(it.QueryPlan, it.QueryStats)
+	if err != nil {
+		return nil, nil, err
+	}
+	return countries, stats, nil
+}
+
+// resultStats extracts the figures of ResultStats from the query statistics. Spanner reports
+// them as strings, the elapsed time with a unit, e.g. "1.23 msecs".
+func resultStats(plan *sppb.QueryPlan, stats map[string]interface{}) (*ResultStats, error) {
+	rs := &ResultStats{Plan: plan, Stats: stats}
+	var err error
+	if rs.RowsReturned, err = statInt(stats, "rows_returned"); err != nil {
+		return nil, err
+	}
+	if rs.BytesReturned, err = statInt(stats, "bytes_returned"); err != nil {
+		return nil, err
+	}
+	if s, ok := stats["elapsed_time"].(string); ok {
+		if rs.Elapsed, err = parseElapsed(s); err != nil {
+			return nil, fmt.Errorf("invalid elapsed_time statistic: %v", err)
+		}
+	}
+	return rs, nil
+}
+
+// statInt returns the integer statistic name, or 0 if it isn't reported.
+func statInt(stats map[string]interface{}, name string) (int64, error) {
+	s, ok := stats[name].(string)
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s statistic %q: %v", name, s, err)
+	}
+	return n, nil
+}
+
+// elapsedUnits maps the units of the elapsed_time statistic to their duration.
+var elapsedUnits = map[string]time.Duration{
+	"usecs": time.Microsecond,
+	"msecs": time.Millisecond,
+	"secs":  time.Second,
+}
+
+// parseElapsed parses a duration such as "1.23 msecs".
+func parseElapsed(s string) (time.Duration, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("%q is not a number and a unit", s)
+	}
+	unit, ok := elapsedUnits[fields[1]]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in %q", s)
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number in %q: %v", s, err)
+	}
+	return time.Duration(v * float64(unit)), nil
+}
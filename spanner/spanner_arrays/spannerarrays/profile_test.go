@@ -0,0 +1,67 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestProfileCountries(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	p, err := ProfileCountries(context.Background(), client, QueryConfig{})
+	if err != nil {
+		t.Fatalf("ProfileCountries: %v", err)
+	}
+	if p.Plan == nil || len(p.Plan.PlanNodes) == 0 {
+		t.Errorf("ProfileCountries returned no query plan")
+	}
+	if p.Stats == nil {
+		t.Fatalf("ProfileCountries returned no query stats")
+	}
+	if got := p.Stats["rows_returned"]; got != "2" {
+		t.Errorf("rows_returned = %v, want 2", got)
+	}
+}
+
+func TestQueryCountriesWithStats(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	countries, stats, err := QueryCountriesWithStats(context.Background(), client)
+	if err != nil {
+		t.Fatalf("QueryCountriesWithStats: %v", err)
+	}
+	if got, want := stats.RowsReturned, int64(len(countries)); got != want || want != 2 {
+		t.Errorf("RowsReturned = %d for %d countries, want 2 for both", got, want)
+	}
+	if stats.Elapsed <= 0 {
+		t.Errorf("Elapsed = %v, want a positive duration", stats.Elapsed)
+	}
+}
+
+func TestParseElapsed(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want time.Duration
+	}{
+		{"1.5 msecs", 1500 * time.Microsecond},
+		{"2 secs", 2 * time.Second},
+		{"250 usecs", 250 * time.Microsecond},
+	} {
+		if got, err := parseElapsed(tc.in); err != nil || got != tc.want {
+			t.Errorf("parseElapsed(%q) = %v, %v, want %v", tc.in, got, err, tc.want)
+		}
+	}
+	for _, in := range []string{"", "1.5", "1.5 fortnights", "soon msecs"} {
+		if _, err := parseElapsed(in); err == nil {
+			t.Errorf("parseElapsed(%q) succeeded, want an error", in)
+		}
+	}
+}
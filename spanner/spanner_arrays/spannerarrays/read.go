@@ -0,0 +1,101 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"sort"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// ReadCountryNames returns the names of all countries in the order of their IDs. It uses the
+// Read API instead of SQL: the table, key set and columns are given directly, so Spanner
+// doesn't have to parse and plan a query, and only the Name column is transferred. Only
+// cities can be soft-deleted, so every country is returned.
+func ReadCountryNames(ctx context.Context, client *spanner.Client) ([]string, error) {
+	it := client.Single().Read(ctx, "Countries", spanner.AllKeys(), []string{"Name"})
+	defer it.Stop()
+
+	names := []string{}
+	for {
+		row, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read countries: %w", err)
+		}
+
+		var name string
+		if err := row.Column(0, &name); err != nil {
+			return nil, fmt.Errorf("failed to read country name: %w", err)
+		}
+		names = append(names, name)
+	}
+}
+
+// ReadCountries returns the same countries as QueryCountries, with the cities of each
+// ordered by name, but reads them with the Read API: all countries and all cities are read
+// from the same read-only transaction in key order and joined on the client, leaving out
+// the soft-deleted cities. It exists to compare the Read API against a SQL query with an
+// ARRAY subquery.
+func ReadCountries(ctx context.Context, client *spanner.Client) ([]Country, error) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	countries := []Country{}
+	index := map[int64]int{}
+	err := iterate(ctx, txn.Read(ctx, "Countries", spanner.AllKeys(), []string{"CountryId", "Name", "Colours", "Founded"}), func(i int, row *spanner.Row) error {
+		var (
+			id      int64
+			country Country
+		)
+		if err := row.Columns(&id, &country.Name, &country.Colours, &country.Founded); err != nil {
+			return err
+		}
+		country.Cities = []spanner.NullString{}
+		index[id] = len(countries)
+		countries = append(countries, country)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read countries: %w", err)
+	}
+
+	err = iterate(ctx, txn.Read(ctx, "Cities", spanner.AllKeys(), []string{"CountryId", "Name", "Deleted"}), func(i int, row *spanner.Row) error {
+		var (
+			countryID int64
+			name      spanner.NullString
+			deleted   spanner.NullBool
+		)
+		if err := row.Columns(&countryID, &name, &deleted); err != nil {
+			return err
+		}
+		// The Read API can't filter rows, so the deleted cities are skipped here.
+		if deleted.Valid && deleted.Bool {
+			return nil
+		}
+		// Cities are interleaved in their country, so every city has one.
+		if j, ok := index[countryID]; ok {
+			countries[j].Cities = append(countries[j].Cities, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cities: %w", err)
+	}
+
+	// Spanner orders NULLs first, and so does this.
+	for _, c := range countries {
+		sort.SliceStable(c.Cities, func(i, j int) bool {
+			a, b := c.Cities[i], c.Cities[j]
+			return !a.Valid && b.Valid || a.Valid && b.Valid && a.StringVal < b.StringVal
+		})
+	}
+	return countries, nil
+}
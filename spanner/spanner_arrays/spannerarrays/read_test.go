@@ -0,0 +1,59 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestReadCountryNames(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	names, err := ReadCountryNames(context.Background(), client)
+	if err != nil {
+		t.Fatalf("ReadCountryNames: %v", err)
+	}
+	// The rows are read in key order: United Kingdom is country 44, Germany 49.
+	if got, want := strings.Join(names, ", "), "United Kingdom, Germany"; got != want {
+		t.Errorf("ReadCountryNames = %q, want %q", got, want)
+	}
+}
+
+func TestReadCountries(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Both must leave out a soft-deleted city.
+	if err := DeleteCity(ctx, client, 49, 101); err != nil {
+		t.Fatalf("DeleteCity: %v", err)
+	}
+	queried, err := QueryCountries(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+	read, err := ReadCountries(ctx, client)
+	if err != nil {
+		t.Fatalf("ReadCountries: %v", err)
+	}
+	// Both order the cities by name, so only the order of the countries may differ.
+	var got, want []string
+	for _, c := range queried {
+		want = append(want, c.String())
+	}
+	for _, c := range read {
+		got = append(got, c.String())
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("ReadCountries = %q, want the countries of QueryCountries %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"log/slog"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+// RestoreDatabase restores the backup backupName, projects/P/instances/I/backups/B, into the
+// new database newDatabaseID of the instance parent and waits until the database can be
+// used. Spanner may still be optimizing the restored database in the background afterwards.
+func RestoreDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, parent, newDatabaseID, backupName string) error {
+	op, err := adminClient.RestoreDatabase(ctx, &adminpb.RestoreDatabaseRequest{
+		Parent:     parent,
+		DatabaseId: newDatabaseID,
+		Source:     &adminpb.RestoreDatabaseRequest_Backup{Backup: backupName},
+	})
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "restoring database", "backup", backupName, "database", newDatabaseID, "operation", op.Name())
+	db, err := op.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("restoring %s failed: %v", backupName, err)
+	}
+	info := db.GetRestoreInfo()
+	if info == nil || info.GetBackupInfo() == nil {
+		return fmt.Errorf("restored database %s has no restore information", db.Name)
+	}
+	backup := info.GetBackupInfo()
+	slog.InfoContext(ctx, "database restored", "database", db.Name, "backup", backup.Backup, "sourceDatabase", backup.SourceDatabase, "backupCreated", backup.CreateTime.AsTime())
+	return nil
+}
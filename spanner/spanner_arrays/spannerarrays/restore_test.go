@@ -0,0 +1,57 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+// fakeRestore is a database admin server which records the restore requests it receives.
+type fakeRestore struct {
+	adminpb.UnimplementedDatabaseAdminServer
+	t        *testing.T
+	requests []*adminpb.RestoreDatabaseRequest
+}
+
+func (f *fakeRestore) RestoreDatabase(ctx context.Context, req *adminpb.RestoreDatabaseRequest) (*longrunningpb.Operation, error) {
+	f.requests = append(f.requests, req)
+	db := &adminpb.Database{
+		Name:  req.Parent + "/databases/" + req.DatabaseId,
+		State: adminpb.Database_READY_OPTIMIZING,
+		RestoreInfo: &adminpb.RestoreInfo{
+			SourceType: adminpb.RestoreSourceType_BACKUP,
+			SourceInfo: &adminpb.RestoreInfo_BackupInfo{BackupInfo: &adminpb.BackupInfo{
+				Backup:         req.GetBackup(),
+				SourceDatabase: req.Parent + "/databases/original",
+			}},
+		},
+	}
+	return doneOperation(f.t, db.Name+"/operations/1", db), nil
+}
+
+func TestRestoreDatabase(t *testing.T) {
+	const (
+		instance = "projects/p/instances/i"
+		backup   = instance + "/backups/b1"
+	)
+	fake := &fakeRestore{t: t}
+	admin, cleanup := newFakeAdminClient(t, fake)
+	defer cleanup()
+
+	if err := RestoreDatabase(context.Background(), admin, instance, "restored", backup); err != nil {
+		t.Fatalf("RestoreDatabase: %v", err)
+	}
+	if len(fake.requests) != 1 {
+		t.Fatalf("server received %d restore requests, want 1", len(fake.requests))
+	}
+	req := fake.requests[0]
+	if req.Parent != instance || req.DatabaseId != "restored" || req.GetBackup() != backup {
+		t.Errorf("restore request = parent %q, database %q, backup %q, want %q, restored, %q", req.Parent, req.DatabaseId, req.GetBackup(), instance, backup)
+	}
+}
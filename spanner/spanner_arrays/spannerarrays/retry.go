@@ -0,0 +1,95 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryConfig controls how an operation which fails with a transient error is retried.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 1 mean a single attempt.
+	MaxAttempts int
+
+	// BaseDelay is the pause after the first failed attempt. It doubles after every
+	// further failure.
+	BaseDelay time.Duration
+
+	// Jitter, when set, shortens every pause by a random amount of up to half of it, so
+	// that clients which failed together don't all retry at the same moment.
+	Jitter bool
+}
+
+// CreateDatabaseWithRetry calls CreateDatabaseWithOptions, retrying it with exponential backoff while it
+// fails with a transient error such as RESOURCE_EXHAUSTED or UNAVAILABLE.
+func CreateDatabaseWithRetry(ctx context.Context, adminClient *database.DatabaseAdminClient, db string, opts DatabaseOptions, cfg RetryConfig) error {
+	return retry(ctx, cfg, func() error {
+		return CreateDatabaseWithOptions(ctx, adminClient, db, opts)
+	})
+}
+
+// retry calls f until it succeeds, fails with an error which is not retryable, cfg.MaxAttempts
+// is reached or ctx is done.
+func retry(ctx context.Context, cfg RetryConfig, f func() error) error {
+	delay := cfg.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		if attempt >= cfg.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		pause := delay
+		if cfg.Jitter && delay > 0 {
+			pause -= time.Duration(rand.Int63n(int64(delay/2) + 1))
+		}
+		slog.DebugContext(ctx, "retrying after transient error", "attempt", attempt, "delay", pause, "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry interrupted: %v)", err, ctx.Err())
+		case <-time.After(pause):
+		}
+		delay *= 2
+	}
+}
+
+// isRetryable reports whether err, or any error it wraps, is a transient gRPC error worth
+// retrying.
+func isRetryable(err error) bool {
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) {
+		return false
+	}
+	switch se.GRPCStatus().Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// readCountriesWithRetry is readCountries for the iterator returned by query, retried as cfg
+// says. query is called again for a fresh iterator on every attempt, since an iterator which
+// failed can't be resumed.
+func readCountriesWithRetry(ctx context.Context, cfg RetryConfig, query func() rowIterator) ([]Country, error) {
+	var countries []Country
+	err := retry(ctx, cfg, func() error {
+		var err error
+		countries, err = readCountries(ctx, query())
+		return err
+	})
+	return countries, err
+}
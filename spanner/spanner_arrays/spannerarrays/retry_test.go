@@ -0,0 +1,117 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flaky returns a function which fails with code the given number of times and then succeeds,
+// together with a pointer to the number of calls made.
+func flaky(failures int, code codes.Code) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= failures {
+			return status.Error(code, "injected failure")
+		}
+		return nil
+	}, &calls
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	cfg := RetryConfig{MaxAttempts: 5, BaseDelay: time.Millisecond}
+
+	f, calls := flaky(2, codes.ResourceExhausted)
+	if err := retry(ctx, cfg, f); err != nil {
+		t.Errorf("retry after 2 transient failures: %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("retry made %d calls, want 3", *calls)
+	}
+
+	f, calls = flaky(10, codes.Unavailable)
+	if err := retry(ctx, cfg, f); err == nil {
+		t.Error("retry with persistent failures succeeded, want error")
+	}
+	if *calls != cfg.MaxAttempts {
+		t.Errorf("retry made %d calls, want MaxAttempts=%d", *calls, cfg.MaxAttempts)
+	}
+
+	f, calls = flaky(1, codes.InvalidArgument)
+	if err := retry(ctx, cfg, f); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("retry with non-retryable error = %v, want InvalidArgument", err)
+	}
+	if *calls != 1 {
+		t.Errorf("retry made %d calls for a non-retryable error, want 1", *calls)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.Unavailable, "down"), true},
+		{fmt.Errorf("failed to read row 1: %w", status.Error(codes.ResourceExhausted, "busy")), true},
+		{fmt.Errorf("failed to read row 1: %w", status.Error(codes.InvalidArgument, "bad")), false},
+		{fmt.Errorf("failed to read row 1: %v", status.Error(codes.Unavailable, "down")), false},
+		{errors.New("not a gRPC error"), false},
+	} {
+		if got := isRetryable(tc.err); got != tc.want {
+			t.Errorf("isRetryable(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestReadCountriesWithRetry(t *testing.T) {
+	ctx := context.Background()
+	cfg := RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, Jitter: true}
+	rows := newRows(t, []string{"Name", "Cities", "Colours"},
+		[]interface{}{"Germany", []string{"Berlin", "Hamburg"}, []string{"black", "red", "gold"}},
+		[]interface{}{"Iceland", []string{}, []string{"blue"}},
+	)
+
+	// The first two queries are rejected with RESOURCE_EXHAUSTED after returning a row, which
+	// must not end up in the result twice.
+	queries := 0
+	query := func() rowIterator {
+		queries++
+		if queries <= 2 {
+			return &sliceIterator{rows: rows[:1], err: status.Error(codes.ResourceExhausted, "injected overload")}
+		}
+		return &sliceIterator{rows: rows}
+	}
+	countries, err := readCountriesWithRetry(ctx, cfg, query)
+	if err != nil {
+		t.Fatalf("readCountriesWithRetry after 2 transient failures: %v", err)
+	}
+	if queries != 3 {
+		t.Errorf("readCountriesWithRetry ran %d queries, want 3", queries)
+	}
+	if len(countries) != 2 || countries[0].Name != "Germany" || countries[1].Name != "Iceland" {
+		t.Errorf("readCountriesWithRetry = %v, want Germany and Iceland", countries)
+	}
+
+	queries = 0
+	query = func() rowIterator {
+		queries++
+		return &sliceIterator{err: status.Error(codes.InvalidArgument, "injected bad query")}
+	}
+	if _, err := readCountriesWithRetry(ctx, cfg, query); err == nil {
+		t.Error("readCountriesWithRetry with an invalid query succeeded, want an error")
+	}
+	if queries != 1 {
+		t.Errorf("readCountriesWithRetry ran %d queries for a non-retryable error, want 1", queries)
+	}
+}
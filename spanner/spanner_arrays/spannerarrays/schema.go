@@ -0,0 +1,98 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/net/context"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+// ApplyDDL runs the schema statements, e.g. CREATE INDEX or ALTER TABLE, against db and waits
+// until Spanner has finished applying all of them.
+func ApplyDDL(ctx context.Context, adminClient *database.DatabaseAdminClient, db string, statements []string) error {
+	if len(statements) == 0 {
+		return nil
+	}
+	op, err := adminClient.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database:   db,
+		Statements: statements,
+	})
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "applying schema change", "database", db, "operation", op.Name(), "statements", len(statements))
+	if err := waitWithProgress(ctx, op, ddlPollInterval); err != nil {
+		return fmt.Errorf("schema change %s failed: %v", op.Name(), err)
+	}
+	slog.InfoContext(ctx, "schema change applied", "database", db, "operation", op.Name())
+	return nil
+}
+
+// ddlPollInterval is how often ApplyDDL checks on a schema change.
+const ddlPollInterval = time.Second
+
+// ddlOperation is the part of *database.UpdateDatabaseDdlOperation used by waitWithProgress.
+type ddlOperation interface {
+	Name() string
+	Poll(ctx context.Context, opts ...gax.CallOption) error
+	Done() bool
+	Metadata() (*adminpb.UpdateDatabaseDdlMetadata, error)
+}
+
+// waitWithProgress polls op every interval until it is done, and logs the progress of each of
+// its statements whenever it changes. Statements which take a while, such as CREATE INDEX
+// on a populated table which has to be backfilled, report the percentage of their work
+// completed, so a long schema change doesn't look like it is hanging.
+func waitWithProgress(ctx context.Context, op ddlOperation, interval time.Duration) error {
+	reported := map[int]int32{}
+	for {
+		if err := op.Poll(ctx); err != nil {
+			return err
+		}
+		// The metadata is only informational, so an undecodable one just isn't logged.
+		if md, err := op.Metadata(); err == nil {
+			for i, p := range md.GetProgress() {
+				percent := p.GetProgressPercent()
+				if last, ok := reported[i]; ok && last == percent {
+					continue
+				}
+				reported[i] = percent
+				var stmt string
+				if i < len(md.GetStatements()) {
+					stmt = md.GetStatements()[i]
+				}
+				slog.InfoContext(ctx, "schema change progress", "operation", op.Name(), "statement", stmt, "percent", percent)
+			}
+		}
+		if op.Done() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ParseDDL splits a semicolon-separated list of schema statements, as found in a DDL file,
+// into the individual statements. Empty statements are dropped.
+func ParseDDL(ddl string) []string {
+	var statements []string
+	for _, stmt := range strings.Split(ddl, ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
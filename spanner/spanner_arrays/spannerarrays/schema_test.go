@@ -0,0 +1,120 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/net/context"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+func TestParseDDL(t *testing.T) {
+	got := ParseDDL(`
+		ALTER TABLE Cities ADD COLUMN Mayor STRING(MAX);
+
+		CREATE INDEX CitiesByPopulation ON Cities(Population);
+	`)
+	want := []string{
+		"ALTER TABLE Cities ADD COLUMN Mayor STRING(MAX)",
+		"CREATE INDEX CitiesByPopulation ON Cities(Population)",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("ParseDDL = %q, want %q", got, want)
+	}
+	if got := ParseDDL(" ; \n"); len(got) != 0 {
+		t.Errorf("ParseDDL(blank) = %q, want no statements", got)
+	}
+}
+
+func TestApplyDDL(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+	admin := newAdminClient(t)
+	defer admin.Close()
+
+	err := ApplyDDL(ctx, admin, client.DatabaseName(), []string{"ALTER TABLE Cities ADD COLUMN Mayor STRING(MAX)"})
+	if err != nil {
+		t.Fatalf("ApplyDDL: %v", err)
+	}
+
+	it := client.Single().Query(ctx, spanner.NewStatement(`
+		SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_NAME = 'Cities' AND COLUMN_NAME = 'Mayor'`))
+	defer it.Stop()
+	row, err := it.Next()
+	if err != nil {
+		t.Fatalf("querying INFORMATION_SCHEMA.COLUMNS: %v", err)
+	}
+	var n int64
+	if err := row.Column(0, &n); err != nil {
+		t.Fatalf("Column(0): %v", err)
+	}
+	if n != 1 {
+		t.Errorf("found %d Cities.Mayor columns after ApplyDDL, want 1", n)
+	}
+}
+
+// progressOperation is a ddlOperation whose single statement reports the next of percents
+// on each poll, and which is done after the last.
+type progressOperation struct {
+	percents []int32
+	polls    int
+}
+
+func (op *progressOperation) Name() string { return "operations/test" }
+
+func (op *progressOperation) Poll(context.Context, ...gax.CallOption) error {
+	op.polls++
+	return nil
+}
+
+func (op *progressOperation) Done() bool { return op.polls >= len(op.percents) }
+
+func (op *progressOperation) Metadata() (*adminpb.UpdateDatabaseDdlMetadata, error) {
+	return &adminpb.UpdateDatabaseDdlMetadata{
+		Statements: []string{"CREATE INDEX CitiesByPopulation ON Cities(Population)"},
+		Progress:   []*adminpb.OperationProgress{{ProgressPercent: op.percents[op.polls-1]}},
+	}, nil
+}
+
+func TestWaitWithProgress(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(old *slog.Logger) { slog.SetDefault(old) }(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	op := &progressOperation{percents: []int32{0, 40, 40, 100}}
+	if err := waitWithProgress(context.Background(), op, time.Millisecond); err != nil {
+		t.Fatalf("waitWithProgress: %v", err)
+	}
+	if op.polls != 4 {
+		t.Errorf("waitWithProgress polled %d times, want 4", op.polls)
+	}
+	logged := buf.String()
+	for _, want := range []string{"percent=0", "percent=40", "percent=100"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log doesn't contain %q:\n%s", want, logged)
+		}
+	}
+	if n := strings.Count(logged, "percent=40"); n != 1 {
+		t.Errorf("progress 40%% logged %d times, want once", n)
+	}
+}
+
+func TestWaitWithProgressCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	op := &progressOperation{percents: []int32{0, 100}}
+	if err := waitWithProgress(ctx, op, time.Hour); err != context.Canceled {
+		t.Errorf("waitWithProgress with cancelled context = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+// QuerySession runs several related queries in one read-only transaction, so that they all
+// see the same snapshot of the database: for example, the cities read for a country returned
+// by Countries are the ones it had when Countries ran, even if they have been changed since.
+// The snapshot is taken by the first query of the session. Writes committed after it are
+// never visible to the session, however long it lives, so create a new one to see them.
+//
+// A QuerySession holds a session of the client's pool until it is closed.
+type QuerySession struct {
+	txn *spanner.ReadOnlyTransaction
+}
+
+// NewQuerySession returns a QuerySession reading from client. Close it when done.
+func NewQuerySession(client *spanner.Client) *QuerySession {
+	return &QuerySession{txn: client.ReadOnlyTransaction()}
+}
+
+// Countries returns every country together with the names of its cities, like
+// QueryCountries.
+func (s *QuerySession) Countries(ctx context.Context) ([]Country, error) {
+	return readCountries(ctx, s.txn.Query(ctx, spanner.NewStatement(countriesSQL)))
+}
+
+// Cities returns the cities of the country identified by countryID, like ListCities.
+func (s *QuerySession) Cities(ctx context.Context, countryID int64) ([]City, error) {
+	return readCities(ctx, s.txn, countryID)
+}
+
+// Close ends the read-only transaction and returns its session to the pool.
+func (s *QuerySession) Close() {
+	s.txn.Close()
+}
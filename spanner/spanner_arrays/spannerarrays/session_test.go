@@ -0,0 +1,42 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestQuerySession(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	s := NewQuerySession(client)
+	defer s.Close()
+	countries, err := s.Countries(ctx)
+	if err != nil {
+		t.Fatalf("Countries: %v", err)
+	}
+	if len(countries) != 2 {
+		t.Fatalf("Countries returned %d countries, want the 2 presets", len(countries))
+	}
+
+	// The city is written after the snapshot of the session has been taken.
+	if err := InsertCity(ctx, client, 49, 103, "Munich"); err != nil {
+		t.Fatalf("InsertCity: %v", err)
+	}
+	cities, err := s.Cities(ctx, 49)
+	if err != nil {
+		t.Fatalf("Cities: %v", err)
+	}
+	if len(cities) != 3 {
+		t.Errorf("Cities(49) in the session returned %d cities, want the 3 read before Munich was inserted", len(cities))
+	}
+	if cities, err := ListCities(ctx, client, 49); err != nil || len(cities) != 4 {
+		t.Errorf("ListCities(49) = %d cities, %v, want 4 including Munich", len(cities), err)
+	}
+}
@@ -0,0 +1,564 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package spannerarrays contains the Cloud Spanner helpers used by the spanner_arrays
+// sample, so that they can be reused outside of the sample program.
+package spannerarrays
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/net/context"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Country describes a country and the cities inside it.
+type Country struct {
+	Name    string
+	Colours []spanner.NullString
+	Cities  []spanner.NullString
+
+	// Founded is the date the country was founded, invalid (NULL) if unknown.
+	Founded spanner.NullDate
+}
+
+// String returns the name of the country followed by its cities, such as
+// "Germany: Berlin, Hamburg". NULL city names are shown as <null>.
+func (c Country) String() string {
+	cities := make([]string, len(c.Cities))
+	for i, city := range c.Cities {
+		cities[i] = city.String()
+	}
+	return c.Name + ": " + strings.Join(cities, ", ")
+}
+
+// MarshalJSON encodes the country as an object with the fields name, colours, cities and
+// founded, in the format written by ExportCountries. The arrays are encoded as arrays of
+// strings, with NULL elements encoded as JSON null, and are never null themselves. founded
+// is a date like "1871-01-18", or null.
+func (c Country) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name    string           `json:"name"`
+		Colours []*string        `json:"colours"`
+		Cities  []*string        `json:"cities"`
+		Founded spanner.NullDate `json:"founded"`
+	}{c.Name, nullStringPointers(c.Colours), nullStringPointers(c.Cities), c.Founded})
+}
+
+// nullStringPointers converts a Spanner string array into pointers which are nil for NULL
+// elements, so that encoding/json writes them as null.
+func nullStringPointers(a []spanner.NullString) []*string {
+	out := make([]*string, len(a))
+	for i, s := range a {
+		if s.Valid {
+			v := s.StringVal
+			out[i] = &v
+		}
+	}
+	return out
+}
+
+// countriesSQLFormat selects each country together with an array of the names of its cities,
+// filtered by the condition substituted for the first %s, ordered by the column substituted
+// for the second and limited by the LIMIT clause, if any, substituted for the third. Without
+// an ORDER BY the array elements would come back in whatever order Spanner happens to read
+// them. The query is valid in both the GoogleSQL and the PostgreSQL dialect.
+const countriesSQLFormat = `
+	SELECT a.Name AS Name, ARRAY(
+		SELECT b.Name FROM Cities b WHERE a.CountryId = b.CountryId%s ORDER BY %s%s
+	) AS Cities, Colours, a.Founded AS Founded FROM Countries a`
+
+// notDeleted is the condition which leaves out the cities b soft-deleted by DeleteCity.
+// Deleted is NULL for cities which were never deleted, so it can't simply be compared with
+// false. Every read of Cities must apply it, unless it asks for the deleted cities too.
+const notDeleted = "b.Deleted IS NOT TRUE"
+
+// notDeletedFilter is notDeleted for appending to a WHERE or ON clause.
+const notDeletedFilter = " AND " + notDeleted
+
+// countriesSQL is the countries query with the cities ordered by name.
+var countriesSQL = CityOrderName.countriesSQL()
+
+// CityOrder is the order of the cities within each country returned by the country queries.
+type CityOrder string
+
+const (
+	// CityOrderName orders the cities by name.
+	CityOrderName CityOrder = "name"
+	// CityOrderID orders the cities by ID.
+	CityOrderID CityOrder = "id"
+)
+
+// ParseCityOrder returns the city order called s, which must be "name" or "id". The empty
+// string means ordering by name.
+func ParseCityOrder(s string) (CityOrder, error) {
+	switch o := CityOrder(s); o {
+	case "":
+		return CityOrderName, nil
+	case CityOrderName, CityOrderID:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown city order %q, must be %s or %s", s, CityOrderName, CityOrderID)
+}
+
+// countriesSQL returns the countries query with the cities ordered by o, leaving out the
+// soft-deleted ones.
+func (o CityOrder) countriesSQL() string {
+	return o.countriesSQLWithDeleted(false)
+}
+
+// countriesSQLWithDeleted is countriesSQL, also including the soft-deleted cities if
+// includeDeleted is set.
+func (o CityOrder) countriesSQLWithDeleted(includeDeleted bool) string {
+	return o.countriesSQLWithLimit(includeDeleted, "")
+}
+
+// countriesSQLWithLimit is countriesSQLWithDeleted with limit, such as " LIMIT @max",
+// appended to the subquery selecting the cities of each country.
+func (o CityOrder) countriesSQLWithLimit(includeDeleted bool, limit string) string {
+	filter := notDeletedFilter
+	if includeDeleted {
+		filter = ""
+	}
+	return fmt.Sprintf(countriesSQLFormat, filter, o.column(), limit)
+}
+
+// column returns the column of the Cities table b which the cities are sorted by.
+func (o CityOrder) column() string {
+	if o == CityOrderID {
+		return "b.CityId"
+	}
+	return "b.Name"
+}
+
+// QueryConfig controls how the country queries are run. The zero value runs a strong read of
+// every country.
+type QueryConfig struct {
+	// Name, when set, restricts the results to the countries with this name.
+	// It is passed as a query parameter, so it is never interpreted as SQL.
+	Name string
+
+	// Staleness, when non-zero, allows the query to read data up to this old. A bounded
+	// stale read can be served by the nearest replica without waiting for the leader,
+	// which lowers latency for reporting workloads, at the cost of possibly missing the
+	// most recent writes. Spanner only supports bounded staleness for single-use
+	// read-only transactions.
+	Staleness time.Duration
+
+	// ReadTimestamp, when set, makes the query read the data exactly as it was committed at
+	// this time, e.g. for point-in-time reporting. Spanner only keeps old versions of the
+	// data for its version retention period, VersionRetention by default, so the timestamp
+	// must lie within it. It can't be combined with Staleness.
+	ReadTimestamp time.Time
+
+	// Dialect is the dialect of the database being queried. The zero value means GoogleSQL.
+	Dialect Dialect
+
+	// RequestTag, when set, is attached to the query so that it can be told apart from other
+	// queries in Spanner's query statistics tables and in Cloud Monitoring.
+	RequestTag string
+
+	// Priority is the priority of the query. The zero value leaves it unspecified.
+	Priority sppb.RequestOptions_Priority
+
+	// CityOrder is the order of the cities within each country. The zero value orders them
+	// by name.
+	CityOrder CityOrder
+
+	// Limit, when positive, is the maximum number of countries returned. The query isn't
+	// changed: the results are stopped after Limit rows, which cancels the stream of the
+	// remaining ones.
+	Limit int
+
+	// MaxCities, when positive, is the maximum number of cities returned per country, the
+	// first ones in CityOrder. It keeps the arrays small for countries with many cities.
+	MaxCities int64
+
+	// IncludeDeleted makes the query return the cities soft-deleted by DeleteCity too.
+	IncludeDeleted bool
+
+	// Cache, when set, holds the results of the query, so that repeating it returns the
+	// same countries without contacting Spanner until they expire or the database is
+	// written to through this package.
+	Cache *QueryCache
+
+	// Retry controls how the query is retried when it fails with a transient error such as
+	// RESOURCE_EXHAUSTED. Every attempt runs the query again from the start, which is safe
+	// because it only reads. The zero value runs it once.
+	Retry RetryConfig
+}
+
+// VersionRetention is the default version retention period of a database: the time for which
+// Spanner keeps the old versions of the data readable.
+const VersionRetention = time.Hour
+
+// validate checks that c describes a read Spanner can serve at time now.
+func (c QueryConfig) validate(now time.Time) error {
+	if c.Limit < 0 {
+		return fmt.Errorf("invalid limit %d, must not be negative", c.Limit)
+	}
+	if c.MaxCities < 0 {
+		return fmt.Errorf("invalid maximum number of cities %d, must not be negative", c.MaxCities)
+	}
+	if c.ReadTimestamp.IsZero() {
+		return nil
+	}
+	if c.Staleness > 0 {
+		return fmt.Errorf("a read timestamp can't be combined with a staleness")
+	}
+	if c.ReadTimestamp.After(now) {
+		return fmt.Errorf("read timestamp %s is in the future", c.ReadTimestamp.Format(time.RFC3339))
+	}
+	if now.Sub(c.ReadTimestamp) > VersionRetention {
+		return fmt.Errorf("read timestamp %s is older than the version retention period of %v, the data is no longer available", c.ReadTimestamp.Format(time.RFC3339), VersionRetention)
+	}
+	return nil
+}
+
+// timestampBound returns the timestamp bound the query runs with, and false if the
+// default strong read should be used.
+func (c QueryConfig) timestampBound() (spanner.TimestampBound, bool) {
+	if !c.ReadTimestamp.IsZero() {
+		return spanner.ReadTimestamp(c.ReadTimestamp), true
+	}
+	if c.Staleness > 0 {
+		return spanner.MaxStaleness(c.Staleness), true
+	}
+	return spanner.StrongRead(), false
+}
+
+// transaction returns the single-use read-only transaction the query runs in.
+func (c QueryConfig) transaction(client *spanner.Client) *spanner.ReadOnlyTransaction {
+	txn := client.Single()
+	if tb, ok := c.timestampBound(); ok {
+		txn = txn.WithTimestampBound(tb)
+	}
+	return txn
+}
+
+// queryOptions returns the options the query runs with.
+func (c QueryConfig) queryOptions() spanner.QueryOptions {
+	return spanner.QueryOptions{RequestTag: c.RequestTag, Priority: c.Priority}
+}
+
+// statement returns the query selecting the countries matched by c. The values of c are only
+// ever passed as parameters; the SQL is assembled from fixed fragments, so no value, such as
+// a country name taken from user input, can change the query.
+func (c QueryConfig) statement() spanner.Statement {
+	params := map[string]interface{}{}
+	// param adds the parameter name with value v and returns its placeholder. PostgreSQL
+	// only has positional parameters, $1, $2, ..., which are passed as p1, p2, ...
+	param := func(name string, v interface{}) string {
+		if c.Dialect == PostgreSQL {
+			n := len(params) + 1
+			params[fmt.Sprintf("p%d", n)] = v
+			return fmt.Sprintf("$%d", n)
+		}
+		params[name] = v
+		return "@" + name
+	}
+
+	var limit string
+	if c.MaxCities > 0 {
+		limit = " LIMIT " + param("max", c.MaxCities)
+	}
+	sql := c.CityOrder.countriesSQLWithLimit(c.IncludeDeleted, limit)
+	if c.Name != "" {
+		sql += " WHERE a.Name = " + param("name", c.Name)
+	}
+	if len(params) == 0 {
+		return spanner.NewStatement(sql)
+	}
+	return spanner.Statement{SQL: sql, Params: params}
+}
+
+// QueryCountries returns every country together with the names of its cities.
+// Each row of the ARRAY(...) query is decoded into a Country with ToStruct.
+func QueryCountries(ctx context.Context, client *spanner.Client) ([]Country, error) {
+	return QueryCountriesWithConfig(ctx, client, QueryConfig{})
+}
+
+// QueryCountriesByName returns the countries called name together with their cities.
+func QueryCountriesByName(ctx context.Context, client *spanner.Client, name string) ([]Country, error) {
+	return QueryCountriesWithConfig(ctx, client, QueryConfig{Name: name})
+}
+
+// QueryCountriesWithConfig returns the countries selected by cfg together with their cities.
+func QueryCountriesWithConfig(ctx context.Context, client *spanner.Client, cfg QueryConfig) (countries []Country, err error) {
+	ctx, span := startSpan(ctx, "spannerarrays.QueryCountries")
+	defer func() {
+		span.SetAttributes(attribute.Int("rows", len(countries)))
+		endSpan(span, err)
+	}()
+
+	if err := cfg.validate(time.Now()); err != nil {
+		return nil, err
+	}
+	query := func() ([]Country, error) {
+		return readCountriesWithRetry(ctx, cfg.Retry, func() rowIterator {
+			it := cfg.transaction(client).QueryWithOptions(ctx, cfg.statement(), cfg.queryOptions())
+			if cfg.Limit > 0 {
+				return &limitIterator{rowIterator: it, n: cfg.Limit}
+			}
+			return it
+		})
+	}
+	if cfg.Cache != nil {
+		return cfg.Cache.countries(client.DatabaseName(), cfg.cacheKey(), query)
+	}
+	return query()
+}
+
+// QueryCountriesByIDs returns the countries whose IDs are in ids, together with their cities.
+// The IDs are passed as a single ARRAY<INT64> query parameter and expanded with UNNEST, so
+// the statement is the same for any number of IDs and can be cached by Spanner. No query is
+// run for an empty slice.
+func QueryCountriesByIDs(ctx context.Context, client *spanner.Client, ids []int64) ([]Country, error) {
+	if len(ids) == 0 {
+		return []Country{}, nil
+	}
+	return readCountries(ctx, client.Single().Query(ctx, spanner.Statement{
+		SQL:    countriesSQL + " WHERE a.CountryId IN UNNEST(@ids)",
+		Params: map[string]interface{}{"ids": ids},
+	}))
+}
+
+// QueryCountriesFoundedBefore returns the countries founded before d, oldest first, together
+// with their cities. d is passed as a DATE query parameter. Countries whose founding date is
+// NULL are never returned, since comparing NULL with a date is neither true nor false.
+func QueryCountriesFoundedBefore(ctx context.Context, client *spanner.Client, d civil.Date) ([]Country, error) {
+	return readCountries(ctx, client.Single().Query(ctx, spanner.Statement{
+		SQL:    countriesSQL + " WHERE a.Founded < @founded ORDER BY a.Founded",
+		Params: map[string]interface{}{"founded": d},
+	}))
+}
+
+// QueryCountriesPage returns at most limit countries, ordered by name, skipping the first
+// offset of them. Fetching consecutive pages with a growing offset pages through all
+// countries.
+func QueryCountriesPage(ctx context.Context, client *spanner.Client, limit, offset int64) ([]Country, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid limit %d, must be positive", limit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset %d, must not be negative", offset)
+	}
+	return readCountries(ctx, client.Single().Query(ctx, spanner.Statement{
+		SQL:    countriesSQL + " ORDER BY a.Name LIMIT @limit OFFSET @offset",
+		Params: map[string]interface{}{"limit": limit, "offset": offset},
+	}))
+}
+
+// rowIterator is the part of *spanner.RowIterator used to read query results.
+type rowIterator interface {
+	Next() (*spanner.Row, error)
+	Stop()
+}
+
+// readCountries decodes every row of it into a Country and stops it. It returns ctx.Err() as
+// soon as ctx is done, even if the iterator still has buffered rows.
+func readCountries(ctx context.Context, it rowIterator) ([]Country, error) {
+	QueriesRun.Add(1)
+	return decodeAll[Country](ctx, it)
+}
+
+// CreateDatabase uses the Spanner database administration client to create the tables used in this demonstration.
+// If the database already exists it is reused as it is, so the sample can be run repeatedly.
+func CreateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, db string) error {
+	return CreateDatabaseWithDialect(ctx, adminClient, db, GoogleSQL)
+}
+
+// CreateDatabaseWithDialect is like CreateDatabase, but creates a database of the given dialect.
+func CreateDatabaseWithDialect(ctx context.Context, adminClient *database.DatabaseAdminClient, db string, dialect Dialect) error {
+	return CreateDatabaseWithOptions(ctx, adminClient, db, DatabaseOptions{Dialect: dialect})
+}
+
+// DatabaseOptions controls how CreateDatabaseWithOptions creates a database. The zero value
+// creates a GoogleSQL database encrypted with a Google-managed key.
+type DatabaseOptions struct {
+	// Dialect is the SQL dialect of the database.
+	Dialect Dialect
+
+	// KMSKeyName, when set, is the Cloud KMS key the database is encrypted with instead of
+	// a Google-managed key (CMEK). It must be the full resource name of the key, in the form
+	// projects/P/locations/L/keyRings/R/cryptoKeys/K. The key has to be in the same location
+	// as the instance, and the Cloud Spanner service agent needs permission to use it.
+	KMSKeyName string
+
+	// Leader, when set, is the default leader region of a database in a multi-region
+	// instance, e.g. "us-east1". It is set with an ALTER DATABASE statement after the schema.
+	Leader string
+
+	// Labels are key/value pairs describing the database, e.g. its owner. Keys must
+	// start with a lowercase letter and, like the values, consist of at most 63 lowercase
+	// letters, digits, underscores and dashes. Unlike instances, Cloud Spanner databases
+	// can't carry labels, so they are only recorded on the trace span and in the log entry
+	// of the creation.
+	Labels map[string]string
+}
+
+var (
+	// labelKeyRE and labelValueRE implement the naming rules for labels.
+	labelKeyRE   = regexp.MustCompile("^[a-z][a-z0-9_-]{0,62}$")
+	labelValueRE = regexp.MustCompile("^[a-z0-9_-]{0,63}$")
+
+	// leaderRE matches the name of a region, such as us-east1.
+	leaderRE = regexp.MustCompile("^[a-z0-9-]+$")
+)
+
+// validate checks the options which are not checked by Spanner before creating the database.
+func (o DatabaseOptions) validate() error {
+	for k, v := range o.Labels {
+		if !labelKeyRE.MatchString(k) {
+			return fmt.Errorf("invalid label key %q: must start with a lowercase letter and contain at most 63 lowercase letters, digits, underscores and dashes", k)
+		}
+		if !labelValueRE.MatchString(v) {
+			return fmt.Errorf("invalid value %q of label %s: must contain at most 63 lowercase letters, digits, underscores and dashes", v, k)
+		}
+	}
+	if o.Leader != "" && !leaderRE.MatchString(o.Leader) {
+		return fmt.Errorf("invalid leader %q, want a region name such as us-east1", o.Leader)
+	}
+	if o.KMSKeyName != "" && !kmsKeyNameRE.MatchString(o.KMSKeyName) {
+		return fmt.Errorf("invalid KMS key name %q, want projects/P/locations/L/keyRings/R/cryptoKeys/K", o.KMSKeyName)
+	}
+	return nil
+}
+
+// statements returns the DDL statements run after CREATE DATABASE for a database called
+// databaseName.
+func (o DatabaseOptions) statements(databaseName string) []string {
+	ddl := o.Dialect.schema()
+	if o.Leader != "" {
+		ddl = append(ddl, o.Dialect.defaultLeaderStatement(databaseName, o.Leader))
+	}
+	return ddl
+}
+
+// kmsKeyNameRE matches the resource name of a Cloud KMS key.
+var kmsKeyNameRE = regexp.MustCompile("^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$")
+
+// createDatabaseRequest returns the request creating db with opts.
+func createDatabaseRequest(db string, opts DatabaseOptions) (*adminpb.CreateDatabaseRequest, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	projectID, databaseName, err := splitDatabase(db)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &adminpb.CreateDatabaseRequest{
+		Parent:          projectID,
+		CreateStatement: opts.Dialect.createStatement(databaseName),
+		DatabaseDialect: opts.Dialect.adminDialect(),
+	}
+	// PostgreSQL databases don't accept extra statements in CreateDatabase, so their
+	// schema is applied in a separate schema update once the database exists.
+	if opts.Dialect != PostgreSQL {
+		req.ExtraStatements = opts.statements(databaseName)
+	}
+	if opts.KMSKeyName != "" {
+		req.EncryptionConfig = &adminpb.EncryptionConfig{KmsKeyName: opts.KMSKeyName}
+	}
+	return req, nil
+}
+
+// CreateDatabaseWithOptions is like CreateDatabase, but creates the database as described by opts.
+func CreateDatabaseWithOptions(ctx context.Context, adminClient *database.DatabaseAdminClient, db string, opts DatabaseOptions) (err error) {
+	ctx, span := startSpan(ctx, "spannerarrays.CreateDatabase", attribute.String("database", db), attribute.String("dialect", string(opts.Dialect)))
+	for k, v := range opts.Labels {
+		span.SetAttributes(attribute.String("label."+k, v))
+	}
+	defer func() { endSpan(span, err) }()
+
+	req, err := createDatabaseRequest(db, opts)
+	if err != nil {
+		return err
+	}
+	exists, err := DatabaseExists(ctx, adminClient, db)
+	if err != nil {
+		return err
+	}
+	if exists {
+		slog.InfoContext(ctx, "database already exists, reusing it", "database", db)
+		return nil
+	}
+	op, err := adminClient.CreateDatabase(ctx, req)
+	if err == nil {
+		_, err = op.Wait(ctx)
+	}
+	// The database may have been created since DatabaseExists, by a concurrent run or by an
+	// earlier attempt of CreateDatabaseWithRetry whose response was lost.
+	if status.Code(err) == codes.AlreadyExists {
+		slog.InfoContext(ctx, "database already exists, reusing it", "database", db)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if opts.Dialect == PostgreSQL {
+		_, databaseName, _ := splitDatabase(db)
+		if err := ApplyDDL(ctx, adminClient, db, opts.statements(databaseName)); err != nil {
+			return err
+		}
+	}
+	if len(opts.Labels) > 0 {
+		slog.InfoContext(ctx, "database labels", "database", db, "labels", opts.Labels)
+	}
+	slog.InfoContext(ctx, "database created", "database", db)
+	return nil
+}
+
+// DatabaseExists reports whether db exists. Spanner answering NotFound means it doesn't;
+// any other error is returned, as it says nothing about the database.
+func DatabaseExists(ctx context.Context, adminClient *database.DatabaseAdminClient, db string) (bool, error) {
+	_, err := adminClient.GetDatabase(ctx, &adminpb.GetDatabaseRequest{Name: db})
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get database %s: %v", db, err)
+	}
+	return true, nil
+}
+
+// DatabaseDDL returns the statements CreateDatabaseWithOptions runs to create db, starting
+// with the CREATE DATABASE statement, without contacting Spanner.
+func DatabaseDDL(db string, opts DatabaseOptions) ([]string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	_, databaseName, err := splitDatabase(db)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{opts.Dialect.createStatement(databaseName)}, opts.statements(databaseName)...), nil
+}
+
+// splitDatabase splits the database name db into the name of its instance and its ID.
+func splitDatabase(db string) (parent, databaseName string, err error) {
+	matches := regexp.MustCompile("^(.*)/databases/(.*)$").FindStringSubmatch(db)
+	if matches == nil || len(matches) != 3 {
+		return "", "", fmt.Errorf("invalid database name %q, want projects/P/instances/I/databases/D", db)
+	}
+	return matches[1], matches[2], nil
+}
+
+// RemoveDatabase deletes the database which this demonstration program created.
+func RemoveDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, db string) error {
+	return adminClient.DropDatabase(ctx, &adminpb.DropDatabaseRequest{Database: db})
+}
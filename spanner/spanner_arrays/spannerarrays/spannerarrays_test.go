@@ -0,0 +1,750 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testOptions returns the client options used by the tests. The clients talk to the
+// Cloud Spanner emulator when SPANNER_EMULATOR_HOST is set.
+func testOptions() []option.ClientOption {
+	if host := os.Getenv(EmulatorHostEnv); host != "" {
+		return EmulatorOptions(host)
+	}
+	return nil
+}
+
+// testInstance returns the instance the tests create their databases in.
+func testInstance(t *testing.T) string {
+	instance := os.Getenv("GOLANG_SAMPLES_SPANNER")
+	if instance == "" {
+		t.Skip("Skipping spanner integration test. Set GOLANG_SAMPLES_SPANNER.")
+	}
+	if !strings.HasPrefix(instance, "projects/") {
+		t.Fatal("Spanner instance ref must be in the form of 'projects/PROJECT_ID/instances/INSTANCE_ID'")
+	}
+	return instance
+}
+
+// newAdminClient returns a database admin client for the tests. The caller must close it.
+func newAdminClient(t *testing.T) *database.DatabaseAdminClient {
+	admin, err := database.NewDatabaseAdminClient(context.Background(), testOptions()...)
+	if err != nil {
+		t.Fatalf("NewDatabaseAdminClient: %v", err)
+	}
+	return admin
+}
+
+// The project and instance newTestClient creates on the emulator. The emulator accepts any
+// names, and emulatorConfig is the only instance configuration it serves.
+const (
+	emulatorProject  = "test-project"
+	emulatorInstance = "test-instance"
+	emulatorConfig   = "emulator-config"
+)
+
+// newTestClient creates a database populated with the preset data and returns a client
+// connected to it, together with a function which closes the client and drops the database.
+// With SPANNER_EMULATOR_HOST set, the database is created on that emulator, creating the
+// instance on it first when needed; otherwise in the instance GOLANG_SAMPLES_SPANNER names.
+// The test is skipped when neither is set.
+func newTestClient(t *testing.T) (*spanner.Client, func()) {
+	host := os.Getenv(EmulatorHostEnv)
+	if host == "" {
+		return openTestDatabase(t, testInstance(t))
+	}
+	if err := CreateInstance(context.Background(), emulatorProject, emulatorInstance, emulatorConfig, 1, EmulatorOptions(host)...); err != nil {
+		t.Fatalf("CreateInstance on emulator %s: %v", host, err)
+	}
+	return openTestDatabase(t, fmt.Sprintf("projects/%s/instances/%s", emulatorProject, emulatorInstance))
+}
+
+// openTestDatabase creates a uniquely named database with the sample schema in instance,
+// loads the presets into it and returns a client together with its cleanup function.
+func openTestDatabase(t *testing.T, instance string) (*spanner.Client, func()) {
+	db := fmt.Sprintf("%s/databases/test-%d", instance, time.Now().UnixNano())
+
+	ctx := context.Background()
+	admin := newAdminClient(t)
+	if err := CreateDatabase(ctx, admin, db); err != nil {
+		admin.Close()
+		t.Fatalf("CreateDatabase(%q): %v", db, err)
+	}
+	client, err := spanner.NewClient(ctx, db, testOptions()...)
+	if err != nil {
+		RemoveDatabase(ctx, admin, db)
+		admin.Close()
+		t.Fatalf("NewClient(%q): %v", db, err)
+	}
+	cleanup := func() {
+		client.Close()
+		if err := RemoveDatabase(ctx, admin, db); err != nil {
+			t.Errorf("RemoveDatabase(%q): %v", db, err)
+		}
+		admin.Close()
+	}
+	if err := LoadPresets(ctx, client); err != nil {
+		cleanup()
+		t.Fatalf("LoadPresets: %v", err)
+	}
+	return client, cleanup
+}
+
+func TestQueryCountries(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	countries, err := QueryCountries(context.Background(), client)
+	if err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+
+	got := map[string][]string{}
+	for _, c := range countries {
+		var cities []string
+		for _, city := range c.Cities {
+			cities = append(cities, city.StringVal)
+		}
+		got[c.Name] = cities
+	}
+	want := map[string][]string{
+		"Germany":        {"Berlin", "Dresden", "Hamburg"},
+		"United Kingdom": {"Bristol", "Liverpool", "London", "Newcastle"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("QueryCountries returned %d countries, want %d", len(got), len(want))
+	}
+	for name, cities := range want {
+		if g := strings.Join(got[name], ","); g != strings.Join(cities, ",") {
+			t.Errorf("cities of %s = %q, want %q", name, g, strings.Join(cities, ","))
+		}
+	}
+}
+
+func TestQueryCountriesByName(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	countries, err := QueryCountriesByName(ctx, client, "Germany")
+	if err != nil {
+		t.Fatalf("QueryCountriesByName(Germany): %v", err)
+	}
+	if len(countries) != 1 || countries[0].Name != "Germany" || len(countries[0].Cities) != 3 {
+		t.Errorf("QueryCountriesByName(Germany) = %v, want Germany with 3 cities", countries)
+	}
+
+	countries, err = QueryCountriesByName(ctx, client, "Atlantis")
+	if err != nil {
+		t.Fatalf("QueryCountriesByName(Atlantis): %v", err)
+	}
+	if len(countries) != 0 {
+		t.Errorf("QueryCountriesByName(Atlantis) = %v, want no countries", countries)
+	}
+}
+
+// injectionName is a country name which would drop the Countries table if it were formatted
+// into the SQL of a query instead of being passed as a parameter.
+const injectionName = "O'Brien; DROP TABLE Countries"
+
+func TestQueryConfigStatementInjection(t *testing.T) {
+	for _, d := range []Dialect{GoogleSQL, PostgreSQL} {
+		stmt := QueryConfig{Name: injectionName, Dialect: d}.statement()
+		if strings.Contains(stmt.SQL, "O'Brien") || strings.Contains(stmt.SQL, "DROP") {
+			t.Errorf("%s statement contains the country name: %q", d, stmt.SQL)
+		}
+		found := false
+		for _, v := range stmt.Params {
+			found = found || v == injectionName
+		}
+		if !found {
+			t.Errorf("%s statement params = %v, want one holding %q", d, stmt.Params, injectionName)
+		}
+	}
+}
+
+func TestQueryCountriesByNameInjection(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := InsertCountry(ctx, client, 353, injectionName); err != nil {
+		t.Fatalf("InsertCountry(%q): %v", injectionName, err)
+	}
+	countries, err := QueryCountriesByName(ctx, client, injectionName)
+	if err != nil {
+		t.Fatalf("QueryCountriesByName(%q): %v", injectionName, err)
+	}
+	if len(countries) != 1 || countries[0].Name != injectionName {
+		t.Errorf("QueryCountriesByName(%q) = %v, want exactly that country", injectionName, countries)
+	}
+	// The table is still there, with the presets and the new country.
+	if countries, err := QueryCountries(ctx, client); err != nil || len(countries) != 3 {
+		t.Errorf("QueryCountries = %d countries, %v, want 3", len(countries), err)
+	}
+}
+
+func TestQueryCountriesLimit(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	countries, err := QueryCountriesWithConfig(ctx, client, QueryConfig{Limit: 1})
+	if err != nil {
+		t.Fatalf("QueryCountriesWithConfig(Limit: 1): %v", err)
+	}
+	if len(countries) != 1 {
+		t.Errorf("QueryCountriesWithConfig(Limit: 1) returned %d countries, want 1", len(countries))
+	}
+	if _, err := QueryCountriesWithConfig(ctx, client, QueryConfig{Limit: -1}); err == nil {
+		t.Error("QueryCountriesWithConfig(Limit: -1) succeeded, want an error")
+	}
+}
+
+func TestQueryCountriesFoundedBefore(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Iceland has no founding date and must not match any query.
+	if err := InsertCountry(ctx, client, 354, "Iceland"); err != nil {
+		t.Fatalf("InsertCountry: %v", err)
+	}
+	countries, err := QueryCountriesFoundedBefore(ctx, client, civil.Date{Year: 1900, Month: time.January, Day: 1})
+	if err != nil {
+		t.Fatalf("QueryCountriesFoundedBefore: %v", err)
+	}
+	var names []string
+	for _, c := range countries {
+		names = append(names, c.Name)
+	}
+	if got, want := strings.Join(names, ", "), "United Kingdom, Germany"; got != want {
+		t.Errorf("QueryCountriesFoundedBefore(1900-01-01) = %q, want %q", got, want)
+	}
+	if len(countries) == 2 && countries[1].Founded.String() != "1871-01-18" {
+		t.Errorf("Germany founded %s, want 1871-01-18", countries[1].Founded)
+	}
+
+	countries, err = QueryCountriesFoundedBefore(ctx, client, civil.Date{Year: 1801, Month: time.January, Day: 1})
+	if err != nil {
+		t.Fatalf("QueryCountriesFoundedBefore: %v", err)
+	}
+	if len(countries) != 0 {
+		t.Errorf("QueryCountriesFoundedBefore(1801-01-01) = %v, want no countries", countries)
+	}
+}
+
+func TestQueryCountriesCityOrder(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		order CityOrder
+		want  string
+	}{
+		{order: "", want: "Berlin, Dresden, Hamburg"},
+		{order: CityOrderName, want: "Berlin, Dresden, Hamburg"},
+		{order: CityOrderID, want: "Berlin, Hamburg, Dresden"},
+	} {
+		countries, err := QueryCountriesWithConfig(context.Background(), client, QueryConfig{Name: "Germany", CityOrder: tc.order})
+		if err != nil {
+			t.Fatalf("QueryCountriesWithConfig(order %q): %v", tc.order, err)
+		}
+		if len(countries) != 1 {
+			t.Fatalf("QueryCountriesWithConfig(Germany, order %q) = %d countries, want 1", tc.order, len(countries))
+		}
+		if got, want := countries[0].String(), "Germany: "+tc.want; got != want {
+			t.Errorf("cities ordered by %q = %q, want %q", tc.order, got, want)
+		}
+	}
+}
+
+func TestQueryCountriesMaxCities(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	countries, err := QueryCountriesWithConfig(context.Background(), client, QueryConfig{Name: "Germany", MaxCities: 2})
+	if err != nil {
+		t.Fatalf("QueryCountriesWithConfig(MaxCities: 2): %v", err)
+	}
+	if len(countries) != 1 {
+		t.Fatalf("QueryCountriesWithConfig(Germany, MaxCities: 2) = %d countries, want 1", len(countries))
+	}
+	if got, want := countries[0].String(), "Germany: Berlin, Dresden"; got != want {
+		t.Errorf("QueryCountriesWithConfig(MaxCities: 2) = %q, want %q", got, want)
+	}
+}
+
+func TestParseCityOrder(t *testing.T) {
+	for s, want := range map[string]CityOrder{"": CityOrderName, "name": CityOrderName, "id": CityOrderID} {
+		if got, err := ParseCityOrder(s); err != nil || got != want {
+			t.Errorf("ParseCityOrder(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseCityOrder("population"); err == nil {
+		t.Error("ParseCityOrder(population) succeeded, want an error")
+	}
+}
+
+func TestQueryCountriesByIDs(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	countries, err := QueryCountriesByIDs(ctx, client, []int64{49})
+	if err != nil {
+		t.Fatalf("QueryCountriesByIDs([49]): %v", err)
+	}
+	if len(countries) != 1 || countries[0].Name != "Germany" {
+		t.Errorf("QueryCountriesByIDs([49]) = %v, want only Germany", countries)
+	}
+
+	countries, err = QueryCountriesByIDs(ctx, client, []int64{44, 49, 1})
+	if err != nil {
+		t.Fatalf("QueryCountriesByIDs([44 49 1]): %v", err)
+	}
+	if len(countries) != 2 {
+		t.Errorf("QueryCountriesByIDs([44 49 1]) = %v, want Germany and United Kingdom", countries)
+	}
+}
+
+func TestQueryCountriesByIDsEmpty(t *testing.T) {
+	// A nil client would panic if a query was run.
+	countries, err := QueryCountriesByIDs(context.Background(), nil, nil)
+	if err != nil || countries == nil || len(countries) != 0 {
+		t.Errorf("QueryCountriesByIDs(no IDs) = %v, %v, want an empty result", countries, err)
+	}
+}
+
+func TestQueryCountriesPage(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		offset int64
+		want   string
+	}{
+		{0, "Germany"},
+		{1, "United Kingdom"},
+	} {
+		countries, err := QueryCountriesPage(ctx, client, 1, tc.offset)
+		if err != nil {
+			t.Fatalf("QueryCountriesPage(1, %d): %v", tc.offset, err)
+		}
+		if len(countries) != 1 || countries[0].Name != tc.want {
+			t.Errorf("QueryCountriesPage(1, %d) = %v, want only %s", tc.offset, countries, tc.want)
+		}
+	}
+
+	countries, err := QueryCountriesPage(ctx, client, 1, 2)
+	if err != nil {
+		t.Fatalf("QueryCountriesPage(1, 2): %v", err)
+	}
+	if len(countries) != 0 {
+		t.Errorf("QueryCountriesPage(1, 2) = %v, want no countries past the end", countries)
+	}
+}
+
+func TestQueryCountriesPageInvalid(t *testing.T) {
+	for _, tc := range []struct{ limit, offset int64 }{{0, 0}, {-1, 0}, {1, -1}} {
+		if _, err := QueryCountriesPage(context.Background(), nil, tc.limit, tc.offset); err == nil {
+			t.Errorf("QueryCountriesPage(%d, %d) succeeded, want an error", tc.limit, tc.offset)
+		}
+	}
+}
+
+func TestQueryCountriesNullCity(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert("Cities", []string{"CountryId", "CityId", "Name", "Population"}, []interface{}{49, 199, spanner.NullString{}, 0}),
+	})
+	if err != nil {
+		t.Fatalf("inserting city with NULL name: %v", err)
+	}
+
+	countries, err := QueryCountriesByName(ctx, client, "Germany")
+	if err != nil {
+		t.Fatalf("QueryCountriesByName(Germany): %v", err)
+	}
+	if len(countries) != 1 {
+		t.Fatalf("QueryCountriesByName(Germany) returned %d countries, want 1", len(countries))
+	}
+	var nulls int
+	for _, c := range countries[0].Cities {
+		if !c.Valid {
+			nulls++
+		}
+	}
+	if nulls != 1 {
+		t.Errorf("Germany has %d NULL cities, want 1", nulls)
+	}
+}
+
+func TestCountryString(t *testing.T) {
+	for _, tc := range []struct {
+		country Country
+		want    string
+	}{
+		{Country{Name: "Germany", Cities: []spanner.NullString{{StringVal: "Berlin", Valid: true}, {StringVal: "Hamburg", Valid: true}}}, "Germany: Berlin, Hamburg"},
+		{Country{Name: "Germany", Cities: []spanner.NullString{{StringVal: "Berlin", Valid: true}, {}}}, "Germany: Berlin, <null>"},
+		{Country{Name: "Iceland"}, "Iceland: "},
+	} {
+		if got := tc.country.String(); got != tc.want {
+			t.Errorf("%+v.String() = %q, want %q", tc.country, got, tc.want)
+		}
+	}
+}
+
+func TestCountryMarshalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		country Country
+		want    string
+	}{
+		{
+			Country{
+				Name:    "Germany",
+				Colours: []spanner.NullString{{StringVal: "black", Valid: true}},
+				Cities:  []spanner.NullString{{StringVal: "Berlin", Valid: true}, {}},
+				Founded: spanner.NullDate{Date: civil.Date{Year: 1871, Month: time.January, Day: 18}, Valid: true},
+			},
+			`{"name":"Germany","colours":["black"],"cities":["Berlin",null],"founded":"1871-01-18"}`,
+		},
+		{Country{Name: "Iceland"}, `{"name":"Iceland","colours":[],"cities":[],"founded":null}`},
+	} {
+		b, err := json.Marshal(tc.country)
+		if err != nil {
+			t.Fatalf("json.Marshal(%+v): %v", tc.country, err)
+		}
+		if got := string(b); got != tc.want {
+			t.Errorf("json.Marshal(%+v) = %s, want %s", tc.country, got, tc.want)
+		}
+	}
+}
+
+func TestQueryConfigTimestampBound(t *testing.T) {
+	if _, ok := (QueryConfig{}).timestampBound(); ok {
+		t.Error("zero QueryConfig uses a timestamp bound, want a strong single-use read")
+	}
+
+	tb, ok := QueryConfig{Staleness: 15 * time.Second}.timestampBound()
+	if !ok {
+		t.Fatal("QueryConfig with staleness does not use a timestamp bound")
+	}
+	if got, want := tb.String(), spanner.MaxStaleness(15*time.Second).String(); got != want {
+		t.Errorf("timestamp bound = %s, want %s", got, want)
+	}
+}
+
+func TestQueryConfigQueryOptions(t *testing.T) {
+	if got := (QueryConfig{}).queryOptions().RequestTag; got != "" {
+		t.Errorf("zero QueryConfig has request tag %q, want none", got)
+	}
+	if got, want := (QueryConfig{RequestTag: "app=test"}).queryOptions().RequestTag, "app=test"; got != want {
+		t.Errorf("request tag = %q, want %q", got, want)
+	}
+	if got, want := (QueryConfig{Priority: sppb.RequestOptions_PRIORITY_LOW}).queryOptions().Priority, sppb.RequestOptions_PRIORITY_LOW; got != want {
+		t.Errorf("priority = %v, want %v", got, want)
+	}
+}
+
+func TestQueryCountriesStale(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	countries, err := QueryCountriesWithConfig(context.Background(), client, QueryConfig{Staleness: time.Second})
+	if err != nil {
+		t.Fatalf("QueryCountriesWithConfig(staleness 1s): %v", err)
+	}
+	if len(countries) > 2 {
+		t.Errorf("stale read returned %d countries, want at most 2", len(countries))
+	}
+}
+
+func TestQueryConfigValidate(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		desc string
+		cfg  QueryConfig
+		ok   bool
+	}{
+		{"strong read", QueryConfig{}, true},
+		{"recent timestamp", QueryConfig{ReadTimestamp: now.Add(-time.Minute)}, true},
+		{"future timestamp", QueryConfig{ReadTimestamp: now.Add(time.Minute)}, false},
+		{"expired timestamp", QueryConfig{ReadTimestamp: now.Add(-2 * VersionRetention)}, false},
+		{"timestamp and staleness", QueryConfig{ReadTimestamp: now.Add(-time.Minute), Staleness: time.Second}, false},
+	} {
+		if err := tc.cfg.validate(now); (err == nil) != tc.ok {
+			t.Errorf("%s: validate() = %v, want ok = %v", tc.desc, err, tc.ok)
+		}
+	}
+}
+
+func TestQueryCountriesAtReadTimestamp(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	ts, err := InsertCityWithTimestamp(ctx, client, 49, 103, "Munich")
+	if err != nil {
+		t.Fatalf("InsertCityWithTimestamp(Munich): %v", err)
+	}
+	if err := InsertCity(ctx, client, 49, 104, "Cologne"); err != nil {
+		t.Fatalf("InsertCity(Cologne): %v", err)
+	}
+
+	countries, err := QueryCountriesWithConfig(ctx, client, QueryConfig{Name: "Germany", ReadTimestamp: ts})
+	if err != nil {
+		t.Fatalf("QueryCountriesWithConfig(read timestamp): %v", err)
+	}
+	if len(countries) != 1 {
+		t.Fatalf("read at %v returned %d countries, want Germany only", ts, len(countries))
+	}
+	var cities []string
+	for _, c := range countries[0].Cities {
+		cities = append(cities, c.StringVal)
+	}
+	sort.Strings(cities)
+	if got, want := strings.Join(cities, ","), "Berlin,Dresden,Hamburg,Munich"; got != want {
+		t.Errorf("cities of Germany read at %v = %s, want %s without the later Cologne", ts, got, want)
+	}
+}
+
+func TestDatabaseExists(t *testing.T) {
+	const db = "projects/p/instances/i/databases/countries"
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		databases []*adminpb.Database
+		getErr    error
+		want      bool
+		wantErr   bool
+	}{
+		{name: "exists", databases: []*adminpb.Database{{Name: db}}, want: true},
+		{name: "not found", databases: []*adminpb.Database{{Name: db + "2"}}, want: false},
+		{name: "error", getErr: status.Error(codes.PermissionDenied, "permission denied"), wantErr: true},
+	} {
+		admin, cleanup := newFakeAdminClient(t, &fakeDatabases{databases: tc.databases, getErr: tc.getErr})
+		got, err := DatabaseExists(ctx, admin, db)
+		cleanup()
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: DatabaseExists = %t, want an error", tc.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: DatabaseExists = %t, %v, want %t", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+// createRace is a fake admin server on which the database appears between GetDatabase and
+// CreateDatabase, as when another run creates it at the same time.
+type createRace struct {
+	fakeDatabases
+}
+
+func (f *createRace) CreateDatabase(ctx context.Context, req *adminpb.CreateDatabaseRequest) (*longrunningpb.Operation, error) {
+	return nil, status.Errorf(codes.AlreadyExists, "database %s already exists", req.CreateStatement)
+}
+
+func TestCreateDatabaseAlreadyExists(t *testing.T) {
+	admin, cleanup := newFakeAdminClient(t, &createRace{})
+	defer cleanup()
+	if err := CreateDatabase(context.Background(), admin, "projects/p/instances/i/databases/countries"); err != nil {
+		t.Errorf("CreateDatabase after a concurrent create: %v, want the database reused", err)
+	}
+}
+
+func TestCreateDatabaseExisting(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+	admin := newAdminClient(t)
+	defer admin.Close()
+
+	if err := CreateDatabase(ctx, admin, client.DatabaseName()); err != nil {
+		t.Fatalf("CreateDatabase(existing database): %v", err)
+	}
+	countries, err := QueryCountries(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+	if len(countries) != 2 {
+		t.Errorf("reused database has %d countries, want the 2 presets", len(countries))
+	}
+
+	// This is what the sample does with --drop-existing.
+	if err := RemoveDatabase(ctx, admin, client.DatabaseName()); err != nil {
+		t.Fatalf("RemoveDatabase: %v", err)
+	}
+	if err := CreateDatabase(ctx, admin, client.DatabaseName()); err != nil {
+		t.Fatalf("CreateDatabase(dropped database): %v", err)
+	}
+	countries, err = QueryCountries(ctx, client)
+	if err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+	if len(countries) != 0 {
+		t.Errorf("recreated database has %d countries, want 0", len(countries))
+	}
+}
+
+func TestEmulator(t *testing.T) {
+	if os.Getenv(EmulatorHostEnv) == "" {
+		t.Skip("Skipping emulator test. Set SPANNER_EMULATOR_HOST.")
+	}
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	countries, err := QueryCountries(context.Background(), client)
+	if err != nil {
+		t.Fatalf("QueryCountries against emulator: %v", err)
+	}
+	if len(countries) != 2 {
+		t.Errorf("QueryCountries against emulator returned %d countries, want 2", len(countries))
+	}
+}
+
+// endlessIterator returns the same row forever and cancels its context after cancelAfter rows.
+type endlessIterator struct {
+	row         *spanner.Row
+	cancel      context.CancelFunc
+	cancelAfter int
+	nexts       int
+	stopped     bool
+}
+
+func (it *endlessIterator) Next() (*spanner.Row, error) {
+	it.nexts++
+	if it.nexts == it.cancelAfter {
+		it.cancel()
+	}
+	return it.row, nil
+}
+
+func (it *endlessIterator) Stop() { it.stopped = true }
+
+func TestReadCountriesCancel(t *testing.T) {
+	row, err := spanner.NewRow([]string{"Name", "Cities", "Colours"}, []interface{}{"Germany", []string{"Berlin"}, []string{"black"}})
+	if err != nil {
+		t.Fatalf("NewRow: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := &endlessIterator{row: row, cancel: cancel, cancelAfter: 3}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := readCountries(ctx, it)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("readCountries after cancel = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("readCountries did not return after its context was cancelled")
+	}
+	if it.nexts != it.cancelAfter {
+		t.Errorf("readCountries read %d rows, want it to stop right after the %dth", it.nexts, it.cancelAfter)
+	}
+	if !it.stopped {
+		t.Error("readCountries did not stop the iterator")
+	}
+}
+
+func TestCreateDatabaseRequestEncryption(t *testing.T) {
+	const db = "projects/p/instances/i/databases/d"
+	req, err := createDatabaseRequest(db, DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("createDatabaseRequest(no key): %v", err)
+	}
+	if req.EncryptionConfig != nil {
+		t.Errorf("EncryptionConfig without a KMS key = %v, want nil", req.EncryptionConfig)
+	}
+
+	const key = "projects/p/locations/us-central1/keyRings/r/cryptoKeys/k"
+	req, err = createDatabaseRequest(db, DatabaseOptions{KMSKeyName: key})
+	if err != nil {
+		t.Fatalf("createDatabaseRequest(%s): %v", key, err)
+	}
+	if req.EncryptionConfig == nil || req.EncryptionConfig.KmsKeyName != key {
+		t.Errorf("EncryptionConfig = %v, want KmsKeyName %s", req.EncryptionConfig, key)
+	}
+
+	for _, bad := range []string{"k", "projects/p/keyRings/r/cryptoKeys/k", key + "/cryptoKeyVersions/1"} {
+		if _, err := createDatabaseRequest(db, DatabaseOptions{KMSKeyName: bad}); err == nil {
+			t.Errorf("createDatabaseRequest(KMS key %q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestCreateDatabaseRequestLeader(t *testing.T) {
+	const db = "projects/p/instances/i/databases/d"
+	req, err := createDatabaseRequest(db, DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("createDatabaseRequest(no leader): %v", err)
+	}
+	for _, stmt := range req.ExtraStatements {
+		if strings.Contains(stmt, "default_leader") {
+			t.Errorf("statement %q sets a default leader, want none without Leader", stmt)
+		}
+	}
+
+	req, err = createDatabaseRequest(db, DatabaseOptions{Leader: "us-east1"})
+	if err != nil {
+		t.Fatalf("createDatabaseRequest(leader us-east1): %v", err)
+	}
+	want := "ALTER DATABASE `d` SET OPTIONS (default_leader = 'us-east1')"
+	if got := req.ExtraStatements[len(req.ExtraStatements)-1]; got != want {
+		t.Errorf("last statement = %q, want %q", got, want)
+	}
+
+	if _, err := createDatabaseRequest(db, DatabaseOptions{Leader: "us-east1'); DROP TABLE Cities; --"}); err == nil {
+		t.Error("createDatabaseRequest(leader with quotes) succeeded, want an error")
+	}
+}
+
+func TestDatabaseOptionsLabels(t *testing.T) {
+	for _, tc := range []struct {
+		labels map[string]string
+		ok     bool
+	}{
+		{map[string]string{"owner": "alice", "env": ""}, true},
+		{map[string]string{"team_1": "db-samples"}, true},
+		{map[string]string{"Owner": "alice"}, false},
+		{map[string]string{"1owner": "alice"}, false},
+		{map[string]string{"owner": "Alice"}, false},
+		{map[string]string{strings.Repeat("k", 64): "v"}, false},
+	} {
+		if err := (DatabaseOptions{Labels: tc.labels}).validate(); (err == nil) != tc.ok {
+			t.Errorf("validate(labels %v) = %v, want ok = %v", tc.labels, err, tc.ok)
+		}
+	}
+}
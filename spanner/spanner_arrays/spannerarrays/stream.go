@@ -0,0 +1,55 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// StreamCountries runs the countries query in the background and sends each country on the
+// returned data channel as soon as it has been decoded. Rows are only read as fast as the
+// consumer receives them.
+//
+// Both channels are closed once the query has finished. If it fails, or ctx is cancelled
+// before all countries have been sent, the error is sent on the error channel first. A
+// consumer which wants to stop early cancels ctx; the query iterator is stopped before the
+// channels are closed, so nothing is leaked.
+func StreamCountries(ctx context.Context, client *spanner.Client) (<-chan Country, <-chan error) {
+	countries := make(chan Country)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(countries)
+		defer close(errc)
+
+		it := client.Single().Query(ctx, spanner.NewStatement(countriesSQL))
+		defer it.Stop()
+		for i := 0; ; i++ {
+			row, err := it.Next()
+			if err == iterator.Done {
+				return
+			}
+			if err != nil {
+				errc <- fmt.Errorf("failed to read row %d: %w", i, err)
+				return
+			}
+			var country Country
+			if err := row.ToStruct(&country); err != nil {
+				errc <- fmt.Errorf("failed to read row %d into Country struct: %w", i, err)
+				return
+			}
+			select {
+			case countries <- country:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return countries, errc
+}
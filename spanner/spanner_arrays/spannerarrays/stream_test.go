@@ -0,0 +1,62 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestStreamCountries(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	countries, errc := StreamCountries(context.Background(), client)
+	var n int
+	for range countries {
+		n++
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("StreamCountries: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("StreamCountries sent %d countries, want 2", n)
+	}
+}
+
+func TestStreamCountriesCancel(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	countries, errc := StreamCountries(ctx, client)
+	if _, ok := <-countries; !ok {
+		t.Fatal("StreamCountries closed the data channel before sending a country")
+	}
+	cancel()
+
+	// The producer stops the iterator before closing the channels, so both being closed
+	// means that its goroutine has returned.
+	timeout := time.After(10 * time.Second)
+	for countries != nil || errc != nil {
+		select {
+		case _, ok := <-countries:
+			if !ok {
+				countries = nil
+			}
+		case _, ok := <-errc:
+			// Depending on where the producer noticed the cancellation, it sends a
+			// context error, an error from the iterator or nothing at all.
+			if !ok {
+				errc = nil
+			}
+		case <-timeout:
+			t.Fatal("StreamCountries did not close its channels after the context was cancelled")
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/net/context"
+)
+
+// recordSpans installs a tracer provider which keeps the ended spans in memory until the
+// returned function restores the previous one.
+func recordSpans() (*tracetest.SpanRecorder, func()) {
+	rec := tracetest.NewSpanRecorder()
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	return rec, func() { otel.SetTracerProvider(old) }
+}
+
+func TestEndSpan(t *testing.T) {
+	rec, restore := recordSpans()
+	defer restore()
+
+	_, span := startSpan(context.Background(), "ok")
+	endSpan(span, nil)
+	_, span = startSpan(context.Background(), "failed")
+	endSpan(span, errors.New("boom"))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if got := spans[0].Status().Code; got != codes.Unset {
+		t.Errorf("status of a successful span = %v, want Unset", got)
+	}
+	if got := spans[1].Status(); got.Code != codes.Error || got.Description != "boom" {
+		t.Errorf("status of a failed span = %v %q, want Error \"boom\"", got.Code, got.Description)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	rec, restore := recordSpans()
+	defer restore()
+
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	if _, err := QueryCountries(context.Background(), client); err != nil {
+		t.Fatalf("QueryCountries: %v", err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		spans[s.Name()] = s
+	}
+	for _, tc := range []struct {
+		span, attr string
+		want       interface{}
+	}{
+		{"spannerarrays.CreateDatabase", "database", client.DatabaseName()},
+		{"spannerarrays.LoadPresets", "mutations", int64(9)},
+		{"spannerarrays.QueryCountries", "rows", int64(2)},
+	} {
+		s, ok := spans[tc.span]
+		if !ok {
+			t.Errorf("no %s span was recorded", tc.span)
+			continue
+		}
+		var got interface{}
+		for _, kv := range s.Attributes() {
+			if string(kv.Key) == tc.attr {
+				got = kv.Value.AsInterface()
+			}
+		}
+		if got != tc.want {
+			t.Errorf("%s attribute %s = %v, want %v", tc.span, tc.attr, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrCityNotFound is returned when a city to be modified does not exist.
+var ErrCityNotFound = errors.New("spannerarrays: city not found")
+
+// IncrementPopulation adds delta to the population of a city.
+//
+// The read and the buffered update run inside a single read-write transaction, so
+// concurrent increments never overwrite each other: if another transaction changes the
+// row in between, Spanner aborts this one and the client library runs the function again.
+func IncrementPopulation(ctx context.Context, client *spanner.Client, countryID, cityID, delta int64) error {
+	return IncrementPopulationWithMaxAttempts(ctx, client, countryID, cityID, delta, 0)
+}
+
+// IncrementPopulationWithMaxAttempts is like IncrementPopulation, but gives up once the
+// transaction has been aborted maxAttempts times, instead of retrying for as long as ctx
+// allows. Zero means no limit.
+func IncrementPopulationWithMaxAttempts(ctx context.Context, client *spanner.Client, countryID, cityID, delta int64, maxAttempts int) error {
+	defer invalidateCache(client)
+	return runWithMaxAttempts(ctx, clientRunner(client), maxAttempts, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		row, err := txn.ReadRow(ctx, "Cities", spanner.Key{countryID, cityID}, []string{"Population"})
+		if spanner.ErrCode(err) == codes.NotFound {
+			return ErrCityNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var population int64
+		if err := row.Column(0, &population); err != nil {
+			return err
+		}
+		return txn.BufferWrite([]*spanner.Mutation{
+			spanner.Update("Cities", []string{"CountryId", "CityId", "Population"}, []interface{}{countryID, cityID, population + delta}),
+		})
+	})
+}
+
+// transactionFunc is the function run by a read-write transaction.
+type transactionFunc func(context.Context, *spanner.ReadWriteTransaction) error
+
+// transactionRunner runs f in a read-write transaction and commits it, running f again
+// whenever the transaction is aborted.
+type transactionRunner func(ctx context.Context, f transactionFunc) error
+
+// clientRunner returns the transactionRunner of client.
+func clientRunner(client *spanner.Client) transactionRunner {
+	return func(ctx context.Context, f transactionFunc) error {
+		_, err := client.ReadWriteTransaction(ctx, f)
+		return err
+	}
+}
+
+// runWithMaxAttempts runs f with run, but fails once f would be run for more than
+// maxAttempts times. The client library retries aborted transactions internally, so every
+// run of f after the first means the previous attempt was aborted. Returning any error
+// other than Aborted from f ends the retries. Zero maxAttempts means no limit.
+func runWithMaxAttempts(ctx context.Context, run transactionRunner, maxAttempts int, f transactionFunc) error {
+	attempts := 0
+	return run(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		attempts++
+		if attempts > 1 {
+			slog.DebugContext(ctx, "read-write transaction aborted", "attempt", attempts-1, "maxAttempts", maxAttempts)
+		}
+		if maxAttempts > 0 && attempts > maxAttempts {
+			return fmt.Errorf("read-write transaction aborted %d times, giving up", maxAttempts)
+		}
+		return f(ctx, txn)
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package spannerarrays
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/net/context"
+)
+
+func TestIncrementPopulation(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	population := func() int64 {
+		row, err := client.Single().ReadRow(ctx, "Cities", spanner.Key{49, 102}, []string{"Population"})
+		if err != nil {
+			t.Fatalf("ReadRow(Cities, 49, 102): %v", err)
+		}
+		var p int64
+		if err := row.Column(0, &p); err != nil {
+			t.Fatalf("Column(Population): %v", err)
+		}
+		return p
+	}
+	before := population()
+
+	const workers = 5
+	var wg sync.WaitGroup
+	errc := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errc <- IncrementPopulation(ctx, client, 49, 102, 10)
+		}()
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		if err != nil {
+			t.Errorf("IncrementPopulation: %v", err)
+		}
+	}
+
+	if got, want := population(), before+workers*10; got != want {
+		t.Errorf("population after %d concurrent increments = %d, want %d", workers, got, want)
+	}
+}
+
+func TestIncrementPopulationNotFound(t *testing.T) {
+	client, cleanup := newTestClient(t)
+	defer cleanup()
+
+	if err := IncrementPopulation(context.Background(), client, 49, 999, 1); err != ErrCityNotFound {
+		t.Errorf("IncrementPopulation(unknown city) = %v, want ErrCityNotFound", err)
+	}
+}
+
+// abortingRunner is a transactionRunner which behaves as if every commit was aborted: it runs
+// f again and again until f fails, or gives up after limit attempts.
+type abortingRunner struct {
+	limit int
+	runs  int
+}
+
+func (r *abortingRunner) run(ctx context.Context, f transactionFunc) error {
+	for r.runs < r.limit {
+		r.runs++
+		if err := f(ctx, nil); err != nil {
+			return err
+		}
+	}
+	return errors.New("aborting runner: transaction never gave up")
+}
+
+func TestRunWithMaxAttempts(t *testing.T) {
+	for _, maxAttempts := range []int{1, 3} {
+		r := &abortingRunner{limit: 100}
+		calls := 0
+		err := runWithMaxAttempts(context.Background(), r.run, maxAttempts, func(context.Context, *spanner.ReadWriteTransaction) error {
+			calls++
+			return nil
+		})
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("aborted %d times", maxAttempts)) {
+			t.Errorf("runWithMaxAttempts(%d) with repeated aborts = %v, want it to give up", maxAttempts, err)
+		}
+		if calls != maxAttempts {
+			t.Errorf("runWithMaxAttempts(%d) ran the transaction %d times, want %d", maxAttempts, calls, maxAttempts)
+		}
+	}
+
+	// Without a limit, the runner decides when to stop.
+	r := &abortingRunner{limit: 5}
+	err := runWithMaxAttempts(context.Background(), r.run, 0, func(context.Context, *spanner.ReadWriteTransaction) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "never gave up") || r.runs != 5 {
+		t.Errorf("runWithMaxAttempts(0) = %v after %d runs, want the runner to retry all 5 times", err, r.runs)
+	}
+
+	// Errors of the transaction function are returned as they are.
+	r = &abortingRunner{limit: 100}
+	err = runWithMaxAttempts(context.Background(), r.run, 3, func(context.Context, *spanner.ReadWriteTransaction) error { return ErrCityNotFound })
+	if err != ErrCityNotFound || r.runs != 1 {
+		t.Errorf("runWithMaxAttempts with a failing function = %v after %d runs, want ErrCityNotFound after 1", err, r.runs)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"golang.org/x/net/context"
+)
+
+// setupTracing installs the OpenTelemetry tracer provider selected by --trace-exporter and
+// returns the function which shuts it down. With stdout, every finished span is written to
+// w as one line of JSON.
+func setupTracing(exporter string, w io.Writer) (shutdown func(context.Context) error, err error) {
+	switch exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter: %v", err)
+		}
+		// The syncer exports each span as soon as it ends, so the spans are interleaved with
+		// the rest of the output in the order they happened.
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+		otel.SetTracerProvider(tp)
+		return tp.Shutdown, nil
+	}
+	return nil, fmt.Errorf("invalid trace exporter %q, want stdout or none", exporter)
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/context"
+)
+
+func TestSetupTracing(t *testing.T) {
+	defer func(old trace.TracerProvider) { otel.SetTracerProvider(old) }(otel.GetTracerProvider())
+
+	var b bytes.Buffer
+	shutdown, err := setupTracing("stdout", &b)
+	if err != nil {
+		t.Fatalf("setupTracing(stdout): %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "spannerarrays.LoadPresets",
+		trace.WithAttributes(attribute.Int("mutations", 9)))
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+
+	var got struct {
+		Name       string
+		Attributes []struct{ Key string }
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("stdout exporter wrote %q, want a JSON span: %v", b.String(), err)
+	}
+	if got.Name != "spannerarrays.LoadPresets" || len(got.Attributes) != 1 || got.Attributes[0].Key != "mutations" {
+		t.Errorf("stdout exporter wrote %q, want the LoadPresets span with its mutations attribute", b.String())
+	}
+
+	if _, err := setupTracing("zipkin", &b); err == nil {
+		t.Error("setupTracing(zipkin) succeeded, want error")
+	}
+}